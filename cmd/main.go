@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +10,10 @@ import (
 	"github.com/bmatiasx/go-task-mgr/internal/cfg"
 	"github.com/bmatiasx/go-task-mgr/internal/client"
 	"github.com/bmatiasx/go-task-mgr/internal/controller"
+	"github.com/bmatiasx/go-task-mgr/internal/github"
+	"github.com/bmatiasx/go-task-mgr/internal/jira"
+	"github.com/bmatiasx/go-task-mgr/internal/local"
+	"github.com/bmatiasx/go-task-mgr/internal/sequence"
 	"github.com/bmatiasx/go-task-mgr/pkg/service"
 )
 
@@ -17,12 +23,49 @@ func main() {
 	log.SetFlags(0)
 	config := cfg.Setup()
 
-	clientConnector := *client.New(config)
-	srv := service.New(clientConnector)
+	cardTypes, categories, err := cfg.LoadCards(config)
+	if err != nil {
+		log.Fatalf("Service could not start: %+v", err.Error())
+	}
+	config.CardTypes, config.Categories = cardTypes, categories
+
+	tracker, err := newTracker(config)
+	if err != nil {
+		log.Fatalf("Service could not start: %+v", err.Error())
+	}
+	seq, err := sequence.New(config.SequenceDBPath)
+	if err != nil {
+		log.Fatalf("Service could not start: %+v", err.Error())
+	}
+	srv := service.New(tracker, seq, config)
 
 	mux := http.NewServeMux()
-	mux.Handle("/", controller.New(srv))
+	handler := controller.New(srv)
+	handler.Timeout, handler.MaxTimeout = config.RequestTimeout, config.MaxRequestTimeout
+	mux.Handle("/", handler)
+	mux.Handle("/debug/vars", expvar.Handler())
 	if err := http.ListenAndServe(":3000", mux); err != nil {
 		log.Fatalf("Service will be shutdown because an error occured: %+v", err.Error())
 	}
 }
+
+func newTracker(config cfg.Config) (service.Tracker, error) {
+	switch config.Tracker {
+	case "trello":
+		c := client.New(config)
+		ctx, cancel := context.WithTimeout(context.Background(), config.RequestTimeout)
+		defer cancel()
+		if err := c.ResolveLabels(ctx); err != nil {
+			return nil, err
+		}
+		return c, nil
+	case "jira":
+		return jira.New(config), nil
+	case "github":
+		return github.New(config), nil
+	case "local":
+		return local.New(config)
+	default:
+		return nil, fmt.Errorf("unknown tracker backend %q", config.Tracker)
+	}
+}
@@ -0,0 +1,395 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+// CardType defines a type of card that can be created with POST /. The
+// built-in issue, bug and task types can be overridden and new types added
+// in the file set by CARDS_CONFIG_FILE.
+type CardType struct {
+	Name string `json:"name"`
+	// Required lists the request fields that must not be empty, e.g. "title".
+	Required []string `json:"required,omitempty"`
+	// List is the workflow state the card is created in, "todo" by default.
+	List string `json:"list,omitempty"`
+	// Labels are tracker label ids, or names for Jira and GitHub.
+	Labels []string `json:"labels,omitempty"`
+	// Title and Description are Go templates, see the README for their fields.
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Categories restricts the categories of the card to some of the configured
+	// ones, any is allowed when empty.
+	Categories []string `json:"categories,omitempty"`
+	// Severity is the default severity. SeverityLists routes severities to
+	// other workflow states than List.
+	Severity      string            `json:"severity,omitempty"`
+	SeverityLists map[string]string `json:"severityLists,omitempty"`
+}
+
+// Category is a task category. It is matched case-insensitively by its name
+// or any of its aliases.
+type Category struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Label is the tracker label id, or name for Jira and GitHub. LabelName
+	// is a Trello label name, resolved to its id at startup.
+	Label     string `json:"label,omitempty"`
+	LabelName string `json:"labelName,omitempty"`
+	// Milestone is the GitHub milestone number the cards are added to.
+	Milestone int `json:"milestone,omitempty"`
+}
+
+// cardsFile is the layout of the CARDS_CONFIG_FILE.
+type cardsFile struct {
+	CardTypes  []CardType `json:"cardTypes"`
+	Categories []Category `json:"categories"`
+}
+
+// requestFields are the request fields a card type can require.
+var requestFields = map[string]bool{
+	"title":       true,
+	"description": true,
+	"category":    true,
+	"severity":    true,
+	"priority":    true,
+	"due":         true,
+	"start":       true,
+	"assignees":   true,
+	"checklist":   true,
+	"reporter":    true,
+	"environment": true,
+	"steps":       true,
+	"links":       true,
+	"fields":      true,
+}
+
+// LoadCards returns the built-in card types and categories, overridden and
+// extended by the ones in the CARDS_CONFIG_FILE when it is set.
+func LoadCards(conf Config) ([]CardType, []Category, error) {
+	types := DefaultCardTypes(conf)
+	categories := DefaultCategories(conf)
+	if conf.CardsConfigFile != "" {
+		b, err := os.ReadFile(conf.CardsConfigFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading cards config, %w", err)
+		}
+		var file cardsFile
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, nil, fmt.Errorf("error parsing cards config %s, %w", conf.CardsConfigFile, err)
+		}
+		for _, t := range file.CardTypes {
+			types = setCardType(types, withDefaults(t))
+		}
+		for _, c := range file.Categories {
+			categories = setCategory(categories, c)
+		}
+	}
+
+	index, err := IndexCategories(categories)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, t := range types {
+		if err := validateCardType(t); err != nil {
+			return nil, nil, fmt.Errorf("invalid card type %q, %w", t.Name, err)
+		}
+		if err := validateStates(conf, t); err != nil {
+			return nil, nil, fmt.Errorf("invalid card type %q, %w", t.Name, err)
+		}
+		for j, name := range t.Categories {
+			c, ok := index[strings.ToLower(name)]
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid card type %q, unknown category %q", t.Name, name)
+			}
+			types[i].Categories[j] = c.Name
+		}
+	}
+	return types, categories, nil
+}
+
+// IndexCategories maps the lower case names and aliases of the categories
+// onto them, failing when two categories share a name or alias.
+func IndexCategories(categories []Category) (map[string]Category, error) {
+	index := make(map[string]Category, len(categories))
+	for _, c := range categories {
+		if strings.TrimSpace(c.Name) == "" {
+			return nil, fmt.Errorf("invalid category, missing name")
+		}
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if other, ok := index[key]; ok {
+				return nil, fmt.Errorf("invalid category %q, %q is already used by %q", c.Name, name, other.Name)
+			}
+			index[key] = c
+		}
+	}
+	return index, nil
+}
+
+// CategoriesFor returns the loaded categories, or the built-in ones of the
+// tracker when the cards config was not loaded.
+func (conf Config) CategoriesFor(tracker string) []Category {
+	if len(conf.Categories) == 0 {
+		conf.Tracker = tracker
+		return DefaultCategories(conf)
+	}
+	return conf.Categories
+}
+
+// CardTypesFor returns the loaded card types, or the built-in ones of the
+// tracker when none were loaded.
+func (conf Config) CardTypesFor(tracker string) []CardType {
+	if len(conf.CardTypes) == 0 {
+		conf.Tracker = tracker
+		return DefaultCardTypes(conf)
+	}
+	return conf.CardTypes
+}
+
+// TypeOf infers the card type from the labels of a card: the type with the
+// most labels, all of them on the card. Cards without the labels of any type
+// are of the first type without labels that requires a category when the
+// card has a category label, or of the first one that does not otherwise.
+func TypeOf(types []CardType, labels []string, categorized bool) string {
+	name, most := "", 0
+	for _, t := range types {
+		if len(t.Labels) > most && containsAll(labels, t.Labels) {
+			name, most = t.Name, len(t.Labels)
+		}
+	}
+	if name != "" {
+		return name
+	}
+
+	fallback := ""
+	for _, t := range types {
+		if len(t.Labels) > 0 {
+			continue
+		}
+		if containsAll(t.Required, []string{"category"}) == categorized {
+			return t.Name
+		}
+		if fallback == "" {
+			fallback = t.Name
+		}
+	}
+	return fallback
+}
+
+// FindCategory finds the category by its name or any of its aliases,
+// ignoring case.
+func FindCategory(categories []Category, name string) (Category, bool) {
+	name = strings.TrimSpace(name)
+	for _, c := range categories {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+		for _, alias := range c.Aliases {
+			if strings.EqualFold(alias, name) {
+				return c, true
+			}
+		}
+	}
+	return Category{}, false
+}
+
+// withDefaults fills in the optional settings of a configured card type.
+func withDefaults(t CardType) CardType {
+	t.Name = strings.ToLower(strings.TrimSpace(t.Name))
+	if t.List == "" {
+		t.List = "todo"
+	}
+	if t.Title == "" {
+		t.Title = "{{.Title}}"
+	}
+	if t.Description == "" {
+		t.Description = DescriptionTemplate
+	}
+	return t
+}
+
+// DefaultCardTypes are the issue, bug and task types, labelled with the
+// label settings of the selected tracker.
+func DefaultCardTypes(conf Config) []CardType {
+	var issueLabel, bugLabel string
+	switch conf.Tracker {
+	case "jira":
+		bugLabel = conf.JiraBugLabel
+	case "github":
+		issueLabel, bugLabel = conf.GitHubIssueLabel, conf.GitHubBugLabel
+	case "local":
+		bugLabel = conf.BugLabelId
+		if bugLabel == "" {
+			bugLabel = "bug"
+		}
+	default:
+		bugLabel = conf.BugLabelId
+	}
+
+	bugTitle := conf.BugTitleTemplate
+	if bugTitle == "" {
+		bugTitle = "{{.Id}}: {{.Summary}}"
+	}
+
+	return []CardType{
+		{
+			Name:        "issue",
+			Required:    []string{"title", "description"},
+			List:        "todo",
+			Labels:      labels(issueLabel),
+			Title:       "{{.Title}}",
+			Description: DescriptionTemplate,
+		},
+		{
+			Name:          "bug",
+			Required:      []string{"description"},
+			List:          "todo",
+			Labels:        labels(bugLabel),
+			Title:         bugTitle,
+			Description:   DescriptionTemplate,
+			Severity:      model.SeverityMedium,
+			SeverityLists: map[string]string{model.SeverityCritical: "doing"},
+		},
+		{
+			Name:        "task",
+			Required:    []string{"title", "category"},
+			List:        "todo",
+			Title:       "{{.Title}}",
+			Description: "Belongs to category {{.Category}}\n\n" + DescriptionTemplate,
+		},
+	}
+}
+
+// DefaultCategories are the Maintenance, Research and Test categories,
+// labelled with the label settings of the selected tracker.
+func DefaultCategories(conf Config) []Category {
+	switch conf.Tracker {
+	case "jira":
+		return []Category{
+			{Name: "Maintenance", Label: conf.JiraMaintenanceLabel},
+			{Name: "Research", Label: conf.JiraResearchLabel},
+			{Name: "Test", Label: conf.JiraTestLabel},
+		}
+	case "github":
+		return []Category{
+			{Name: "Maintenance", Label: conf.GitHubMaintenanceLabel, Milestone: conf.GitHubMaintenanceMilestone},
+			{Name: "Research", Label: conf.GitHubResearchLabel, Milestone: conf.GitHubResearchMilestone},
+			{Name: "Test", Label: conf.GitHubTestLabel, Milestone: conf.GitHubTestMilestone},
+		}
+	default:
+		return []Category{
+			{Name: "Maintenance", Label: conf.MaintenanceLabelId},
+			{Name: "Research", Label: conf.ResearchLabelId},
+			{Name: "Test", Label: conf.TestLabelId},
+		}
+	}
+}
+
+func validateCardType(t CardType) error {
+	if t.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	for _, field := range t.Required {
+		if !requestFields[field] {
+			return fmt.Errorf("unknown required field %q", field)
+		}
+	}
+	if t.Severity != "" && !validSeverity(t.Severity) {
+		return fmt.Errorf("unknown severity %q", t.Severity)
+	}
+	for severity := range t.SeverityLists {
+		if !validSeverity(severity) {
+			return fmt.Errorf("unknown severity %q in severityLists", severity)
+		}
+	}
+	for name, text := range map[string]string{"title": t.Title, "description": t.Description} {
+		if _, err := ParseTemplate(name, text); err != nil {
+			return fmt.Errorf("invalid %s template, %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateStates checks the workflow has the states the cards are created
+// in. Jira and GitHub create issues in their initial status whatever the
+// state, the other trackers need the list of the state.
+func validateStates(conf Config, t CardType) error {
+	if conf.Tracker == "jira" || conf.Tracker == "github" {
+		return nil
+	}
+	states := []string{t.List}
+	for _, severity := range []string{model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow} {
+		if state, ok := t.SeverityLists[severity]; ok {
+			states = append(states, state)
+		}
+	}
+	for _, state := range states {
+		if _, ok := conf.Workflow[strings.ToLower(state)]; ok {
+			continue
+		}
+		if env, ok := listEnvs[strings.ToLower(state)]; ok {
+			return fmt.Errorf("state %q has no list, set %s or add it to WORKFLOW_STATES", state, env)
+		}
+		return fmt.Errorf("unknown state %q, add it to WORKFLOW_STATES", state)
+	}
+	return nil
+}
+
+func validSeverity(severity string) bool {
+	switch severity {
+	case model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow:
+		return true
+	}
+	return false
+}
+
+// setCategory replaces the category with the same name or appends it.
+func setCategory(categories []Category, c Category) []Category {
+	for i := range categories {
+		if strings.EqualFold(categories[i].Name, c.Name) {
+			categories[i] = c
+			return categories
+		}
+	}
+	return append(categories, c)
+}
+
+// setCardType replaces the type with the same name or appends it.
+func setCardType(types []CardType, t CardType) []CardType {
+	for i := range types {
+		if types[i].Name == t.Name {
+			types[i] = t
+			return types
+		}
+	}
+	return append(types, t)
+}
+
+func labels(label string) []string {
+	if label == "" {
+		return nil
+	}
+	return []string{label}
+}
+
+func containsAll(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, v := range values {
+			if v == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCardsConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "cards.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var testWorkflow = map[string]string{"todo": "1", "doing": "2", "backlog": "5"}
+
+func TestLoadCards(t *testing.T) {
+	path := writeCardsConfig(t, `{"cardTypes": [
+		{"name": "Spike", "required": ["title"], "labels": ["55"]},
+		{"name": "issue", "required": ["title"], "list": "backlog"}
+	]}`)
+
+	types, _, err := LoadCards(Config{BugLabelId: "10", CardsConfigFile: path, Workflow: testWorkflow})
+
+	assert.NoError(t, err)
+	assert.Len(t, types, 4)
+	assert.Equal(t, CardType{Name: "issue", Required: []string{"title"}, List: "backlog", Title: "{{.Title}}", Description: DescriptionTemplate}, types[0])
+	assert.Equal(t, []string{"10"}, types[1].Labels)
+	assert.Equal(t, CardType{Name: "spike", Required: []string{"title"}, List: "todo", Labels: []string{"55"}, Title: "{{.Title}}", Description: DescriptionTemplate}, types[3])
+}
+
+func TestLoadCards_Categories(t *testing.T) {
+	path := writeCardsConfig(t, `{
+		"categories": [
+			{"name": "research", "aliases": ["spike", "R&D"], "label": "22"},
+			{"name": "Security", "labelName": "security"}
+		],
+		"cardTypes": [{"name": "audit", "categories": ["SECURITY", "spike"]}]
+	}`)
+
+	types, categories, err := LoadCards(Config{MaintenanceLabelId: "11", CardsConfigFile: path, Workflow: testWorkflow})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Category{
+		{Name: "Maintenance", Label: "11"},
+		{Name: "research", Aliases: []string{"spike", "R&D"}, Label: "22"},
+		{Name: "Test"},
+		{Name: "Security", LabelName: "security"},
+	}, categories)
+	assert.Equal(t, []string{"Security", "research"}, types[3].Categories)
+}
+
+func TestLoadCards_Invalid(t *testing.T) {
+	for _, content := range []string{
+		`{"cardTypes": [{"name": "spike", "required": ["owner"]}]}`,
+		`{"cardTypes": [{"name": "spike", "title": "{{.Title"}]}`,
+		`{"cardTypes": [{"name": "spike", "description": "{{checklist .Steps}}"}]}`,
+		`{"cardTypes": [{"name": "spike", "severityLists": {"blocker": "doing"}}]}`,
+		`{"cardTypes": [{"required": ["title"]}]}`,
+		`{"cardTypes": {}}`,
+		`{"cardTypes": [{"name": "spike", "categories": ["Security"]}]}`,
+		`{"categories": [{"name": "Ops", "aliases": ["test"]}]}`,
+		`{"categories": [{"aliases": ["ops"]}]}`,
+		`{"cardTypes": [{"name": "spike", "list": "doign"}]}`,
+		`{"cardTypes": [{"name": "spike", "severityLists": {"high": "review"}}]}`,
+	} {
+		_, _, err := LoadCards(Config{CardsConfigFile: writeCardsConfig(t, content), Workflow: testWorkflow})
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoadCards_States(t *testing.T) {
+	// Critical bugs go to doing, which has no list
+	_, _, err := LoadCards(Config{Workflow: map[string]string{"todo": "1"}})
+	assert.EqualError(t, err, `invalid card type "bug", state "doing" has no list, set DOING_LIST_ID or add it to WORKFLOW_STATES`)
+
+	// Jira and GitHub issues start in their initial status
+	for _, tracker := range []string{"jira", "github"} {
+		_, _, err := LoadCards(Config{Tracker: tracker})
+		assert.NoError(t, err, tracker)
+	}
+
+	// The lists of the local database are named after the states
+	_, _, err = LoadCards(Config{Tracker: "local", Workflow: workflow(Config{Tracker: "local"})})
+	assert.NoError(t, err)
+}
+
+func TestTypeOf(t *testing.T) {
+	types := append(DefaultCardTypes(Config{Tracker: "local"}),
+		CardType{Name: "incident", Labels: []string{"bug", "incident"}},
+		CardType{Name: "spike", Labels: []string{"spike"}})
+
+	for _, test := range []struct {
+		labels      []string
+		categorized bool
+		cardType    string
+	}{
+		{labels: nil, cardType: "issue"},
+		{labels: []string{"maintenance"}, categorized: true, cardType: "task"},
+		{labels: []string{"bug"}, cardType: "bug"},
+		{labels: []string{"incident", "bug"}, cardType: "incident"},
+		{labels: []string{"spike", "research"}, categorized: true, cardType: "spike"},
+		{labels: []string{"incident"}, cardType: "issue"},
+	} {
+		assert.Equal(t, test.cardType, TypeOf(types, test.labels, test.categorized), "labels %v", test.labels)
+	}
+}
@@ -1,32 +1,244 @@
 package cfg
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
+	Tracker            string
 	URL                string
 	APIKey             string
 	Token              string
 	AppPort            string
+	BoardId            string
 	ToDoListId         string
 	DoingListId        string
+	DoneListId         string
+	BlockedListId      string
 	BugLabelId         string
 	MaintenanceLabelId string
 	ResearchLabelId    string
 	TestLabelId        string
+	// SeverityLabelIds and PriorityLabelIds map the bug severities and
+	// priorities onto tracker label ids.
+	SeverityLabelIds map[string]string
+	PriorityLabelIds map[string]string
+	// BugTitleTemplate is a text/template for the bug titles, see the README.
+	BugTitleTemplate string
+	SequenceDBPath   string
+	// CardsConfigFile is the JSON file with the card types and categories,
+	// CardTypes and Categories the ones loaded from it together with the
+	// built-in ones.
+	CardsConfigFile string
+	CardTypes       []CardType
+	Categories      []Category
+	// Retries is how many times failed Trello calls are retried, waiting
+	// from RetryBaseDelay up to RetryMaxDelay between attempts.
+	Retries        int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// RateLimit is how many Trello calls are made per RateInterval, calls
+	// over it wait up to RateMaxWait for their turn.
+	RateLimit    int
+	RateInterval time.Duration
+	RateMaxWait  time.Duration
+	// BreakerFailures is how many Trello calls in a row fail before calls
+	// stop for BreakerCooldown.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// RequestTimeout bounds the tracker calls of each request. Requests may
+	// ask for another timeout, up to MaxRequestTimeout.
+	RequestTimeout    time.Duration
+	MaxRequestTimeout time.Duration
+	// Workflow maps the named states cards can transition to onto tracker list ids.
+	Workflow map[string]string
+	Jira
+	GitHub
+	Local
+}
+
+// Jira holds the settings of the Jira Cloud tracker backend.
+type Jira struct {
+	JiraURL              string
+	JiraEmail            string
+	JiraToken            string
+	JiraProjectKey       string
+	JiraComponent        string
+	JiraBugLabel         string
+	JiraMaintenanceLabel string
+	JiraResearchLabel    string
+	JiraTestLabel        string
+	// JiraIssueTypes maps card types onto Jira issue types, e.g. "incident=Incident".
+	JiraIssueTypes map[string]string
+}
+
+// GitHub holds the settings of the GitHub Issues tracker backend.
+type GitHub struct {
+	GitHubURL                  string
+	GitHubToken                string
+	GitHubRepository           string
+	GitHubIssueLabel           string
+	GitHubBugLabel             string
+	GitHubMaintenanceLabel     string
+	GitHubResearchLabel        string
+	GitHubTestLabel            string
+	GitHubMaintenanceMilestone int
+	GitHubResearchMilestone    int
+	GitHubTestMilestone        int
+}
+
+// Local holds the settings of the embedded database backend.
+type Local struct {
+	LocalDBPath  string
+	LocalBaseURL string
 }
 
 func Setup() Config {
 	conf := Config{
+		Tracker:            getEnv("TRACKER", "trello"),
 		URL:                os.Getenv("TRELLO_CARDS_URL"),
 		APIKey:             os.Getenv("TRELLO_API_KEY"),
 		Token:              os.Getenv("TRELLO_TOKEN"),
 		AppPort:            os.Getenv("APP_PORT"),
+		Retries:            getEnvInt("TRELLO_RETRIES", 3),
+		RetryBaseDelay:     getEnvDuration("TRELLO_RETRY_BASE_DELAY", 200*time.Millisecond),
+		RetryMaxDelay:      getEnvDuration("TRELLO_RETRY_MAX_DELAY", 5*time.Second),
+		RateLimit:          getEnvInt("TRELLO_RATE_LIMIT", 100),
+		RateInterval:       getEnvDuration("TRELLO_RATE_INTERVAL", 10*time.Second),
+		RateMaxWait:        getEnvDuration("TRELLO_RATE_MAX_WAIT", 5*time.Second),
+		BreakerFailures:    getEnvInt("TRELLO_BREAKER_FAILURES", 5),
+		BreakerCooldown:    getEnvDuration("TRELLO_BREAKER_COOLDOWN", 30*time.Second),
+		BoardId:            os.Getenv("BOARD_ID"),
 		ToDoListId:         os.Getenv("TO_DO_LIST_ID"),
 		DoingListId:        os.Getenv("DOING_LIST_ID"),
+		DoneListId:         os.Getenv("DONE_LIST_ID"),
+		BlockedListId:      os.Getenv("BLOCKED_LIST_ID"),
 		BugLabelId:         os.Getenv("BUG_LABEL_ID"),
 		MaintenanceLabelId: os.Getenv("MAINTENANCE_LABEL_ID"),
 		ResearchLabelId:    os.Getenv("RESEARCH_LABEL_ID"),
 		TestLabelId:        os.Getenv("TEST_LABEL_ID"),
+		BugTitleTemplate:   os.Getenv("BUG_TITLE_TEMPLATE"),
+		SequenceDBPath:     getEnv("SEQUENCE_DB_PATH", "data/sequence.db"),
+		CardsConfigFile:    os.Getenv("CARDS_CONFIG_FILE"),
+		RequestTimeout:     getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		MaxRequestTimeout:  getEnvDuration("MAX_REQUEST_TIMEOUT", 60*time.Second),
+		Jira: Jira{
+			JiraURL:              os.Getenv("JIRA_URL"),
+			JiraEmail:            os.Getenv("JIRA_EMAIL"),
+			JiraToken:            os.Getenv("JIRA_API_TOKEN"),
+			JiraProjectKey:       os.Getenv("JIRA_PROJECT_KEY"),
+			JiraComponent:        os.Getenv("JIRA_COMPONENT"),
+			JiraBugLabel:         getEnv("JIRA_BUG_LABEL", "bug"),
+			JiraMaintenanceLabel: getEnv("JIRA_MAINTENANCE_LABEL", "maintenance"),
+			JiraResearchLabel:    getEnv("JIRA_RESEARCH_LABEL", "research"),
+			JiraTestLabel:        getEnv("JIRA_TEST_LABEL", "test"),
+			JiraIssueTypes:       getEnvMap("JIRA_ISSUE_TYPES"),
+		},
+		GitHub: GitHub{
+			GitHubURL:                  getEnv("GITHUB_API_URL", "https://api.github.com"),
+			GitHubToken:                os.Getenv("GITHUB_TOKEN"),
+			GitHubRepository:           os.Getenv("GITHUB_REPOSITORY"),
+			GitHubIssueLabel:           getEnv("GITHUB_ISSUE_LABEL", "enhancement"),
+			GitHubBugLabel:             getEnv("GITHUB_BUG_LABEL", "bug"),
+			GitHubMaintenanceLabel:     getEnv("GITHUB_MAINTENANCE_LABEL", "maintenance"),
+			GitHubResearchLabel:        getEnv("GITHUB_RESEARCH_LABEL", "research"),
+			GitHubTestLabel:            getEnv("GITHUB_TEST_LABEL", "test"),
+			GitHubMaintenanceMilestone: getEnvInt("GITHUB_MAINTENANCE_MILESTONE", 0),
+			GitHubResearchMilestone:    getEnvInt("GITHUB_RESEARCH_MILESTONE", 0),
+			GitHubTestMilestone:        getEnvInt("GITHUB_TEST_MILESTONE", 0),
+		},
+		Local: Local{
+			LocalDBPath:  getEnv("LOCAL_DB_PATH", "data/cards.db"),
+			LocalBaseURL: getEnv("LOCAL_BASE_URL", "http://localhost:3000"),
+		},
 	}
+	conf.SeverityLabelIds = getEnvMap("SEVERITY_LABEL_IDS")
+	conf.PriorityLabelIds = getEnvMap("PRIORITY_LABEL_IDS")
+	conf.Workflow = workflow(conf)
 	return conf
 }
+
+// listEnvs are the variables with the list ids of the well known states.
+var listEnvs = map[string]string{
+	"todo":    "TO_DO_LIST_ID",
+	"doing":   "DOING_LIST_ID",
+	"done":    "DONE_LIST_ID",
+	"blocked": "BLOCKED_LIST_ID",
+}
+
+// workflow builds the state to list mapping from the well known list ids,
+// overridden or extended by WORKFLOW_STATES, e.g. "review=5f1c...,done=5f1d...".
+// The local database has no list ids, its lists are named after the states.
+func workflow(conf Config) map[string]string {
+	states := map[string]string{}
+	for state, listId := range map[string]string{
+		"todo":    conf.ToDoListId,
+		"doing":   conf.DoingListId,
+		"done":    conf.DoneListId,
+		"blocked": conf.BlockedListId,
+	} {
+		if listId == "" && conf.Tracker == "local" {
+			listId = state
+		}
+		if listId != "" {
+			states[state] = listId
+		}
+	}
+	for state, listId := range getEnvMap("WORKFLOW_STATES") {
+		states[strings.ToLower(state)] = listId
+	}
+	return states
+}
+
+func getEnv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid value %q for %s, using %d", v, key, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid value %q for %s, using %s", v, key, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getEnvMap parses a comma separated list of key=value pairs.
+func getEnvMap(key string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			if strings.TrimSpace(pair) != "" {
+				log.Printf("invalid pair %q in %s, ignoring it", pair, key)
+			}
+			continue
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m
+}
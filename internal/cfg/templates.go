@@ -0,0 +1,55 @@
+package cfg
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DescriptionTemplate is the default description of the card types. It lays
+// the request fields out in Markdown sections, leaving out the empty ones.
+const DescriptionTemplate = `{{.Description}}{{with .Environment}}
+
+### Environment
+{{.}}{{end}}{{with .Steps}}
+
+### Steps to reproduce
+{{numbered .}}{{end}}{{with .Links}}
+
+### Links
+{{bullets .}}{{end}}{{with .Reporter}}
+
+Reported by {{.}}{{end}}`
+
+// templateFuncs are the functions the title and description templates can use.
+var templateFuncs = template.FuncMap{
+	"bullets":  bullets,
+	"numbered": numbered,
+}
+
+// ParseTemplate parses a title or description template of a card type.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// bullets renders the items as a Markdown list, one per line.
+func bullets(items []string) string {
+	var lines []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			lines = append(lines, "- "+item)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// numbered renders the items as a Markdown ordered list, one per line.
+func numbered(items []string) string {
+	var lines []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			lines = append(lines, fmt.Sprintf("%d. %s", len(lines)+1, item))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
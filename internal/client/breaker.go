@@ -0,0 +1,122 @@
+package client
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+// outcome is what a call tells the breaker about Trello.
+type outcome int
+
+const (
+	// succeeded calls got an answer, even an error one like 404.
+	succeeded outcome = iota
+	// failed calls got no answer or a server error.
+	failed
+	// skipped calls were not sent or were cancelled by the caller, so they
+	// say nothing about Trello.
+	skipped
+)
+
+// breaker stops calling Trello while it is down, so requests fail fast
+// instead of waiting for their timeout. It opens after threshold calls in a
+// row failed, and after cooldown lets a single call through to probe
+// Trello: the circuit closes again if it succeeds, or stays open for
+// another cooldown otherwise.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+// newBreaker is nil, never opening, when the threshold is not set.
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     model.CircuitClosed,
+		now:       time.Now,
+	}
+}
+
+// allow lets the call through unless the circuit is open, or half open and
+// already probing. The call must report its outcome with done.
+func (b *breaker) allow() (done func(outcome), err error) {
+	if b == nil {
+		return func(outcome) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == model.CircuitOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = model.CircuitHalfOpen
+	}
+	switch {
+	case b.state == model.CircuitOpen:
+		retryAt := b.openedAt.Add(b.cooldown)
+		return nil, fmt.Errorf("Trello is unavailable after %d failed calls, circuit open until %s: %w", b.failures, retryAt.Format(time.RFC3339), model.ErrUnavailable)
+	case b.state == model.CircuitHalfOpen && b.probing:
+		return nil, fmt.Errorf("Trello is unavailable, waiting for a probe call to finish: %w", model.ErrUnavailable)
+	case b.state == model.CircuitHalfOpen:
+		b.probing = true
+	}
+	return b.done, nil
+}
+
+func (b *breaker) done(result outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	probe := b.state == model.CircuitHalfOpen && b.probing
+	if probe {
+		b.probing = false
+	}
+
+	switch result {
+	case succeeded:
+		if b.state != model.CircuitClosed {
+			log.Printf("Trello answered, closing the circuit")
+		}
+		b.state, b.failures = model.CircuitClosed, 0
+	case failed:
+		b.failures++
+		if probe || (b.state == model.CircuitClosed && b.failures >= b.threshold) {
+			log.Printf("Trello failed %d calls in a row, opening the circuit for %v", b.failures, b.cooldown)
+			b.state, b.openedAt = model.CircuitOpen, b.now()
+		}
+	}
+}
+
+// health reports the state of the circuit.
+func (b *breaker) health() model.TrackerHealth {
+	if b == nil {
+		return model.TrackerHealth{Circuit: model.CircuitClosed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	health := model.TrackerHealth{Circuit: b.state, Failures: b.failures}
+	if b.state == model.CircuitOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		if !b.now().Before(retryAt) {
+			health.Circuit = model.CircuitHalfOpen
+		} else {
+			health.RetryAt = &retryAt
+		}
+	}
+	return health
+}
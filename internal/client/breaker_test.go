@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	b := newBreaker(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	call := func(result outcome) error {
+		done, err := b.allow()
+		if err == nil {
+			done(result)
+		}
+		return err
+	}
+
+	// Failures in a row open the circuit, an answer in between resets them
+	assert.NoError(t, call(failed))
+	assert.NoError(t, call(succeeded))
+	assert.NoError(t, call(failed))
+	assert.NoError(t, call(skipped))
+	assert.NoError(t, call(failed))
+	assert.Equal(t, model.CircuitOpen, b.health().Circuit)
+
+	// While open calls fail fast
+	err := call(succeeded)
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Contains(t, err.Error(), "circuit open until 2023-04-01T12:00:30Z")
+	retryAt := now.Add(30 * time.Second)
+	assert.Equal(t, model.TrackerHealth{Circuit: model.CircuitOpen, Failures: 2, RetryAt: &retryAt}, b.health())
+
+	// After the cooldown a single probe goes through, a failed one opens it again
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, model.CircuitHalfOpen, b.health().Circuit)
+	done, err := b.allow()
+	assert.NoError(t, err)
+	assert.ErrorIs(t, call(succeeded), model.ErrUnavailable)
+	done(failed)
+	assert.Equal(t, model.CircuitOpen, b.health().Circuit)
+
+	// and a successful one closes it
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, call(succeeded))
+	assert.Equal(t, model.TrackerHealth{Circuit: model.CircuitClosed}, b.health())
+}
+
+func TestBreaker_SkippedProbe(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	b := newBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	done, _ := b.allow()
+	done(failed)
+	now = now.Add(time.Second)
+
+	// A probe cancelled by its caller lets the next call probe
+	done, err := b.allow()
+	assert.NoError(t, err)
+	done(skipped)
+	done, err = b.allow()
+	assert.NoError(t, err)
+	done(succeeded)
+	assert.Equal(t, model.CircuitClosed, b.health().Circuit)
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	attempts := 0
+	c := New(testConfig)
+	c.client = &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		attempts++
+		return response(http.StatusServiceUnavailable, "")
+	})}
+	c.breaker = newBreaker(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+		assert.ErrorIs(t, err, model.ErrUnavailable)
+	}
+	_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	// Once open the calls are not sent to Trello
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Contains(t, err.Error(), "circuit open")
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, model.CircuitOpen, c.Health().Circuit)
+}
+
+func TestClient_CircuitBreaker_ClientErrors(t *testing.T) {
+	c := New(testConfig)
+	c.client = &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		return response(http.StatusNotFound, "")
+	})}
+	c.breaker = newBreaker(1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+		assert.ErrorIs(t, err, model.ErrNotFound)
+	}
+
+	// Trello answered, so the circuit stays closed
+	assert.Equal(t, model.CircuitClosed, c.Health().Circuit)
+}
+
+func TestClient_CircuitBreaker_ShortTimeout(t *testing.T) {
+	// Trello stand-in answering in 50ms
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"id": "6423991687731e2e9e1fec60"}`))
+	}))
+	defer server.Close()
+
+	c := New(testConfig)
+	c.URL = server.URL
+	c.breaker = newBreaker(3, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(model.WithShortTimeout(context.Background()), 5*time.Millisecond)
+		_, err := c.GetCard(ctx, "6423991687731e2e9e1fec60")
+		cancel()
+		assert.ErrorIs(t, err, model.ErrTimeout)
+	}
+
+	// The callers asked for less time than the default, so the circuit
+	// stays closed for everyone else
+	assert.Equal(t, model.CircuitClosed, c.Health().Circuit)
+	_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+	assert.NoError(t, err)
+
+	// Running out of the default timeout still counts as a failure
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, _ = c.GetCard(ctx, "6423991687731e2e9e1fec60")
+		cancel()
+	}
+	assert.Equal(t, model.CircuitOpen, c.Health().Circuit)
+}
@@ -2,44 +2,109 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
-	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bmatiasx/go-task-mgr/internal/cfg"
 	"github.com/bmatiasx/go-task-mgr/internal/model"
 )
 
-const cardsPath = "1/cards"
+const (
+	cardsPath      = "1/cards"
+	boardsPath     = "1/boards"
+	listsPath      = "1/lists"
+	searchPath     = "1/search"
+	checklistsPath = "1/checklists"
 
-//type Connector interface {
-//	Do(req *http.Request) (*http.Response, error)
-//}
+	cardFields  = "name,desc,url,idBoard,idList,idLabels,idMembers,due,start,closed"
+	searchLimit = 1000
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
 
+// Client is the Trello implementation of the service tracker backend.
 type Client struct {
 	URL     string
 	APIKey  string
 	Token   string
 	AppPort string
+	BoardId string
 	TaskIds
 	LabelIds
-	client *http.Client
+	client  *http.Client
+	retry   retryPolicy
+	limiter *limiter
+	breaker *breaker
+	members *memberCache
+	fields  *fieldCache
+}
+
+// card is the Trello representation of a card.
+type card struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	Desc      string     `json:"desc"`
+	Url       string     `json:"url"`
+	IdBoard   string     `json:"idBoard"`
+	IdList    string     `json:"idList"`
+	IdLabels  []string   `json:"idLabels"`
+	IdMembers []string   `json:"idMembers"`
+	Due       *time.Time `json:"due"`
+	Start     *time.Time `json:"start"`
+	Closed    bool       `json:"closed"`
+}
+
+// action is the Trello representation of a card comment.
+type action struct {
+	Id   string    `json:"id"`
+	Date time.Time `json:"date"`
+	Data struct {
+		Text string `json:"text"`
+	} `json:"data"`
+	MemberCreator struct {
+		Username string `json:"username"`
+	} `json:"memberCreator"`
+}
+
+// attachment is the Trello representation of a card attachment.
+type attachment struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Url      string `json:"url"`
+	Bytes    int64  `json:"bytes"`
+	MimeType string `json:"mimeType"`
+}
+
+// checklist is the Trello representation of a checklist. Check items share
+// their field names with model.CheckItem so they are decoded directly.
+type checklist struct {
+	Id         string            `json:"id"`
+	Name       string            `json:"name"`
+	CheckItems []model.CheckItem `json:"checkItems"`
 }
 
 type TaskIds struct {
-	ToDoListId  string
-	DoingListId string
-	BugLabelId  string
+	ToDoListId string
+	BugLabelId string
 }
 
 type LabelIds struct {
-	MaintenanceLabelId string
-	ResearchLabelId    string
-	TestLabelId        string
+	CardTypes        []cfg.CardType
+	Categories       []cfg.Category
+	SeverityLabelIds map[string]string
+	PriorityLabelIds map[string]string
 }
 
 func New(cfg cfg.Config) *Client {
@@ -48,99 +113,438 @@ func New(cfg cfg.Config) *Client {
 		APIKey:  cfg.APIKey,
 		Token:   cfg.Token,
 		AppPort: cfg.AppPort,
+		BoardId: cfg.BoardId,
 		TaskIds: TaskIds{
-			ToDoListId:  cfg.ToDoListId,
-			DoingListId: cfg.DoingListId,
-			BugLabelId:  cfg.BugLabelId,
+			ToDoListId: cfg.ToDoListId,
+			BugLabelId: cfg.BugLabelId,
 		},
 		LabelIds: LabelIds{
-			MaintenanceLabelId: cfg.MaintenanceLabelId,
-			ResearchLabelId:    cfg.ResearchLabelId,
-			TestLabelId:        cfg.TestLabelId,
+			CardTypes:        cfg.CardTypesFor("trello"),
+			Categories:       categories(cfg),
+			SeverityLabelIds: cfg.SeverityLabelIds,
+			PriorityLabelIds: cfg.PriorityLabelIds,
 		},
-		client: &http.Client{
-			Timeout: time.Duration(10) * time.Second,
+		client: &http.Client{},
+		retry: retryPolicy{
+			Retries:   cfg.Retries,
+			BaseDelay: cfg.RetryBaseDelay,
+			MaxDelay:  cfg.RetryMaxDelay,
 		},
+		limiter: newLimiter(cfg.RateLimit, cfg.RateInterval, cfg.RateMaxWait),
+		breaker: newBreaker(cfg.BreakerFailures, cfg.BreakerCooldown),
+		members: &memberCache{},
+		fields:  &fieldCache{},
 	}
 	return &c
 }
 
-func (c *Client) CreateIssue(request model.Issue) (*model.Card, error) {
-	url := fmt.Sprintf("%s/%s?idList=%s&key=%s&token=%s", c.URL, cardsPath, c.ToDoListId, c.APIKey, c.Token)
-	log.Printf("creating an issue with Trello API with url: %s", url)
+// CreateCard adds the card to its list, labelled with the labels of its type
+// and those of its category, severity and priority.
+func (c *Client) CreateCard(ctx context.Context, request model.NewCard) (*model.Card, error) {
+	listId := request.ListId
+	if listId == "" {
+		listId = c.ToDoListId
+	}
+	url := fmt.Sprintf("%s/%s?idList=%s&key=%s&token=%s", c.URL, cardsPath, listId, c.APIKey, c.Token)
+	log.Printf("creating a %s with Trello API with url: %s \nand title: %s", request.Type, url, request.Title)
 
+	labels := append([]string{}, request.Labels...)
+	for _, label := range []string{
+		c.setLabel(request.Category),
+		c.SeverityLabelIds[request.Severity],
+		c.PriorityLabelIds[request.Priority],
+	} {
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
 	payload := map[string]string{
 		"name": request.Title,
 		"desc": request.Description,
 	}
-	issueResp := model.Card{}
+	if len(labels) > 0 {
+		payload["idLabels"] = strings.Join(labels, ",")
+	}
+	setSchedule(payload, request.Schedule)
+	if err := c.setMembers(ctx, payload, request.Assignees); err != nil {
+		return nil, err
+	}
+	cardResp := card{}
+
+	err := c.call(ctx, payload, &cardResp, http.MethodPost, url)
+	if err != nil {
+		log.Printf("error while creating a %s", request.Type)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return cardResp.toCard(), nil
+}
+
+func (c *Client) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s?key=%s&token=%s", c.URL, cardsPath, id, c.APIKey, c.Token)
+	log.Printf("getting card %s from Trello API", id)
+
+	cardResp := card{}
+
+	err := c.call(ctx, nil, &cardResp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting card %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return cardResp.toCard(), nil
+}
+
+func (c *Client) UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s?key=%s&token=%s", c.URL, cardsPath, id, c.APIKey, c.Token)
+	log.Printf("updating card %s with Trello API", id)
+
+	payload := map[string]string{}
+	if update.Title != "" {
+		payload["name"] = update.Title
+	}
+	if update.Description != "" {
+		payload["desc"] = update.Description
+	}
+	if len(update.Labels) > 0 {
+		payload["idLabels"] = strings.Join(update.Labels, ",")
+	}
+	if update.ListId != "" {
+		payload["idList"] = update.ListId
+	}
+	cardResp := card{}
 
-	err := c.call(payload, &issueResp, http.MethodPost, url)
+	err := c.call(ctx, payload, &cardResp, http.MethodPut, url)
 	if err != nil {
-		log.Printf("error while creating an issue")
-		return nil, fmt.Errorf("error: %s", err.Error())
+		log.Printf("error while updating card %s", id)
+		return nil, fmt.Errorf("error: %w", err)
 	}
-	return &issueResp, nil
+	return cardResp.toCard(), nil
 }
 
-func (c *Client) CreateBug(request model.Bug) (*model.Card, error) {
-	url := fmt.Sprintf("%s/%s?idList=%s&key=%s&token=%s", c.URL, cardsPath, c.DoingListId, c.APIKey, c.Token)
-	bugTitle := makeBugTitle()
-	log.Printf("creating a bug with Trello API with url: %s \nand title: %s", url, bugTitle)
+func (c *Client) ArchiveCard(ctx context.Context, id string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s?key=%s&token=%s", c.URL, cardsPath, id, c.APIKey, c.Token)
+	log.Printf("archiving card %s with Trello API", id)
+
+	payload := map[string]bool{
+		"closed": true,
+	}
+	cardResp := card{}
+
+	err := c.call(ctx, payload, &cardResp, http.MethodPut, url)
+	if err != nil {
+		log.Printf("error while archiving card %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return cardResp.toCard(), nil
+}
+
+func (c *Client) MoveCard(ctx context.Context, id string, listId string) (*model.Card, error) {
+	return c.UpdateCard(ctx, id, model.CardUpdate{ListId: listId})
+}
+
+func (c *Client) DeleteCard(ctx context.Context, id string) error {
+	url := fmt.Sprintf("%s/%s/%s?key=%s&token=%s", c.URL, cardsPath, id, c.APIKey, c.Token)
+	log.Printf("deleting card %s with Trello API", id)
+
+	err := c.call(ctx, nil, nil, http.MethodDelete, url)
+	if err != nil {
+		log.Printf("error while deleting card %s", id)
+		return fmt.Errorf("error: %w", err)
+	}
+	return nil
+}
+
+// ListCards returns the open cards of the board matching the query. Free text
+// goes through the search API, the other filters are applied on the results.
+func (c *Client) ListCards(ctx context.Context, query model.CardQuery) ([]model.Card, error) {
+	var endpoint string
+	switch {
+	case query.Text != "":
+		endpoint = fmt.Sprintf("%s/%s?query=%s&idBoards=%s&modelTypes=cards&cards_limit=%d&card_fields=%s&key=%s&token=%s",
+			c.URL, searchPath, url.QueryEscape(query.Text), c.BoardId, searchLimit, cardFields, c.APIKey, c.Token)
+	case query.ListId != "":
+		endpoint = fmt.Sprintf("%s/%s/%s/cards?fields=%s&key=%s&token=%s", c.URL, listsPath, query.ListId, cardFields, c.APIKey, c.Token)
+	default:
+		endpoint = fmt.Sprintf("%s/%s/%s/cards/open?fields=%s&key=%s&token=%s", c.URL, boardsPath, c.BoardId, cardFields, c.APIKey, c.Token)
+	}
+	log.Printf("listing cards with Trello API")
+
+	var found []card
+	var err error
+	if query.Text != "" {
+		searchResp := struct {
+			Cards []card `json:"cards"`
+		}{}
+		err = c.call(ctx, nil, &searchResp, http.MethodGet, endpoint)
+		found = searchResp.Cards
+	} else {
+		err = c.call(ctx, nil, &found, http.MethodGet, endpoint)
+	}
+	if err != nil {
+		log.Printf("error while listing cards")
+		return nil, fmt.Errorf("error: %w", err)
+	}
+
+	cards := []model.Card{}
+	for _, f := range found {
+		if c.matches(f, query) {
+			cards = append(cards, *f.toCard())
+		}
+	}
+	return cards, nil
+}
+
+func (c *Client) AddComment(ctx context.Context, cardId string, text string) (*model.Comment, error) {
+	url := fmt.Sprintf("%s/%s/%s/actions/comments?key=%s&token=%s", c.URL, cardsPath, cardId, c.APIKey, c.Token)
+	log.Printf("adding a comment to card %s with Trello API", cardId)
 
 	payload := map[string]string{
-		"name":     bugTitle,
-		"desc":     request.Description,
-		"idLabels": c.BugLabelId,
+		"text": text,
 	}
-	bugResp := model.Card{}
+	commentResp := action{}
 
-	err := c.call(payload, &bugResp, http.MethodPost, url)
+	err := c.call(ctx, payload, &commentResp, http.MethodPost, url)
 	if err != nil {
-		log.Printf("error while creating a bug")
-		return nil, fmt.Errorf("error: %s", err.Error())
+		log.Printf("error while adding a comment to card %s", cardId)
+		return nil, fmt.Errorf("error: %w", err)
 	}
-	return &bugResp, nil
+	return commentResp.toComment(), nil
 }
 
-func (c *Client) CreateTask(request model.Task) (*model.Card, error) {
-	url := fmt.Sprintf("%s/%s?idList=%s&key=%s&token=%s", c.URL, cardsPath, c.ToDoListId, c.APIKey, c.Token)
-	log.Printf("creating a task with Trello API with url: %s", url)
+func (c *Client) ListComments(ctx context.Context, cardId string) ([]model.Comment, error) {
+	url := fmt.Sprintf("%s/%s/%s/actions?filter=commentCard&key=%s&token=%s", c.URL, cardsPath, cardId, c.APIKey, c.Token)
+	log.Printf("getting the comments of card %s from Trello API", cardId)
+
+	var actions []action
+
+	err := c.call(ctx, nil, &actions, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting the comments of card %s", cardId)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+
+	comments := make([]model.Comment, 0, len(actions))
+	for _, a := range actions {
+		comments = append(comments, *a.toComment())
+	}
+	return comments, nil
+}
 
-	label := c.setLabel(request.Category)
+func (c *Client) AddAttachment(ctx context.Context, cardId string, file model.File) (*model.Attachment, error) {
+	url := fmt.Sprintf("%s/%s/%s/attachments?key=%s&token=%s", c.URL, cardsPath, cardId, c.APIKey, c.Token)
+	log.Printf("attaching %s to card %s with Trello API", file.Name, cardId)
+
+	attachmentResp := attachment{}
+
+	err := c.upload(ctx, file, &attachmentResp, url)
+	if err != nil {
+		log.Printf("error while attaching %s to card %s", file.Name, cardId)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return attachmentResp.toAttachment(), nil
+}
+
+// AddChecklist creates the checklist on the card and then its check items, one call each.
+func (c *Client) AddChecklist(ctx context.Context, cardId string, name string, items []string) (*model.Checklist, error) {
+	url := fmt.Sprintf("%s/%s?idCard=%s&key=%s&token=%s", c.URL, checklistsPath, cardId, c.APIKey, c.Token)
+	log.Printf("adding a checklist to card %s with Trello API", cardId)
 
 	payload := map[string]string{
-		"name":     request.Title,
-		"desc":     fmt.Sprintf("Belongs to category %s", request.Category),
-		"idLabels": label,
+		"name": name,
 	}
-	taskResp := model.Card{}
+	checklistResp := checklist{}
 
-	err := c.call(payload, &taskResp, http.MethodPost, url)
+	err := c.call(ctx, payload, &checklistResp, http.MethodPost, url)
 	if err != nil {
-		log.Printf("error while creating a task")
-		return nil, fmt.Errorf("error: %s", err.Error())
+		log.Printf("error while adding a checklist to card %s", cardId)
+		return nil, fmt.Errorf("error: %w", err)
 	}
-	return &taskResp, nil
+
+	for _, item := range items {
+		url := fmt.Sprintf("%s/%s/%s/checkItems?key=%s&token=%s", c.URL, checklistsPath, checklistResp.Id, c.APIKey, c.Token)
+
+		payload := map[string]string{
+			"name": item,
+		}
+		itemResp := model.CheckItem{}
+
+		err := c.call(ctx, payload, &itemResp, http.MethodPost, url)
+		if err != nil {
+			log.Printf("error while adding a check item to checklist %s", checklistResp.Id)
+			return nil, fmt.Errorf("error: %w", err)
+		}
+		checklistResp.CheckItems = append(checklistResp.CheckItems, itemResp)
+	}
+	return checklistResp.toChecklist(), nil
+}
+
+func (c *Client) ListChecklists(ctx context.Context, cardId string) ([]model.Checklist, error) {
+	url := fmt.Sprintf("%s/%s/%s/checklists?key=%s&token=%s", c.URL, cardsPath, cardId, c.APIKey, c.Token)
+	log.Printf("getting the checklists of card %s from Trello API", cardId)
+
+	var checklistsResp []checklist
+
+	err := c.call(ctx, nil, &checklistsResp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting the checklists of card %s", cardId)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+
+	checklists := make([]model.Checklist, 0, len(checklistsResp))
+	for _, cl := range checklistsResp {
+		checklists = append(checklists, *cl.toChecklist())
+	}
+	return checklists, nil
 }
 
-func (c *Client) call(request interface{}, response *model.Card, httpMethod string, url string) error {
+func (c *Client) SetCheckItem(ctx context.Context, cardId string, itemId string, complete bool) (*model.CheckItem, error) {
+	url := fmt.Sprintf("%s/%s/%s/checkItem/%s?key=%s&token=%s", c.URL, cardsPath, cardId, itemId, c.APIKey, c.Token)
+	log.Printf("updating check item %s of card %s with Trello API", itemId, cardId)
+
+	payload := map[string]string{
+		"state": model.CheckItemIncomplete,
+	}
+	if complete {
+		payload["state"] = model.CheckItemComplete
+	}
+	itemResp := model.CheckItem{}
 
-	b, err := json.Marshal(request)
+	err := c.call(ctx, payload, &itemResp, http.MethodPut, url)
 	if err != nil {
-		return fmt.Errorf("error marshaling request")
+		log.Printf("error while updating check item %s", itemId)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return &itemResp, nil
+}
+
+func (c *Client) call(ctx context.Context, request interface{}, response interface{}, httpMethod string, url string) error {
+
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("error marshaling request")
+		}
+		body = bytes.NewReader(b)
 	}
 
-	req, err := http.NewRequest(httpMethod, url, bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, httpMethod, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request, %w", err)
+	}
 	req.Header.Add("Content-Type", "application/json")
+
+	return c.send(req, response)
+}
+
+// upload posts the file as multipart/form-data, the only encoding Trello
+// accepts for attachments.
+func (c *Client) upload(ctx context.Context, file model.File, response interface{}, url string) error {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(file.Name)))
+	header.Set("Content-Type", file.MimeType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("error creating multipart request, %w", err)
+	}
+	if _, err = io.Copy(part, file.Content); err != nil {
+		return fmt.Errorf("error copying file to request, %w", err)
+	}
+	for field, value := range map[string]string{"name": file.Name, "mimeType": file.MimeType} {
+		if err = writer.WriteField(field, value); err != nil {
+			return fmt.Errorf("error creating multipart request, %w", err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("error creating multipart request, %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return fmt.Errorf("error creating request, %w", err)
 	}
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+
+	return c.send(req, response)
+}
 
-	resp, err := c.client.Do(req)
+// send makes the request unless the circuit breaker is open, retrying it
+// as the retry policy allows. Every attempt waits for the rate limiter.
+func (c *Client) send(req *http.Request, response interface{}) error {
+
+	done, err := c.breaker.allow()
 	if err != nil {
-		return fmt.Errorf("error sending request, %w", err)
+		log.Printf("request not sent: %v", err)
+		return err
 	}
+	result, err := c.try(req, response)
+	done(result)
+	return err
+}
+
+// try makes the attempts of the request and tells the outcome of the last
+// one.
+func (c *Client) try(req *http.Request, response interface{}) (outcome, error) {
+
+	result := skipped
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(req.Context()); err != nil {
+			log.Printf("request not sent: %v", err)
+			return result, err
+		}
+		resp, err := c.client.Do(req)
+		switch {
+		case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+			result = skipped
+		case err != nil && errors.Is(req.Context().Err(), context.DeadlineExceeded) && model.ShortTimeout(req.Context()):
+			// The caller gave Trello less time than the default timeout
+			result = skipped
+		case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+			result = failed
+		default:
+			result = succeeded
+		}
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			c.limiter.drain()
+		}
+
+		delay, retry := c.retry.next(req, resp, err, attempt)
+		if !retry {
+			if err != nil {
+				return result, model.TransportError(err)
+			}
+			return result, c.read(resp, response)
+		}
+
+		if err != nil {
+			log.Printf("request error: %v", err)
+		} else {
+			log.Printf("response error, code: %v", resp.StatusCode)
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.Printf("retrying %s %s in %v, attempt %d of %d failed", req.Method, req.URL.Path, delay, attempt, c.retry.Retries+1)
+
+		if err := wait(req.Context(), delay); err != nil {
+			return result, model.TransportError(err)
+		}
+		if req, err = rewind(req); err != nil {
+			return result, fmt.Errorf("error creating request, %w", err)
+		}
+	}
+}
+
+// Health reports the circuit breaker state of the client.
+func (c *Client) Health() model.TrackerHealth {
+	return c.breaker.health()
+}
+
+func (c *Client) read(resp *http.Response, response interface{}) error {
+	defer resp.Body.Close()
 
 	output, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -150,10 +554,12 @@ func (c *Client) call(request interface{}, response *model.Card, httpMethod stri
 	switch {
 	case !c.isSuccess(resp.StatusCode):
 		log.Printf("response error, code: %v", resp.StatusCode)
-		return fmt.Errorf("error returned from external API")
+		return model.UpstreamError(resp.StatusCode)
 	default:
 		log.Printf("successful client response. code: %v", resp.StatusCode)
-		_ = json.Unmarshal(output, &response)
+		if response != nil {
+			_ = json.Unmarshal(output, response)
+		}
 	}
 
 	return nil
@@ -172,16 +578,90 @@ func (c *Client) successCodes() map[int]string {
 	}
 }
 
-func makeBugTitle() string {
-	n := rand.Intn(999-0) + 0
-	return fmt.Sprintf("bug-critical-%v", n)
+func (c *Client) matches(f card, query model.CardQuery) bool {
+	if f.Closed || (query.ListId != "" && f.IdList != query.ListId) {
+		return false
+	}
+	if query.Category != "" && !hasLabel(f.IdLabels, c.setLabel(query.Category)) {
+		return false
+	}
+	return query.Type == "" || query.Type == c.cardType(f.IdLabels)
 }
 
-func (c *Client) setLabel(category string) string {
-	categoryToLabel := map[string]string{
-		"Maintenance": c.MaintenanceLabelId,
-		"Research":    c.ResearchLabelId,
-		"Test":        c.TestLabelId,
+// cardType tells the card type apart from the labels set when it was created.
+func (c *Client) cardType(labels []string) string {
+	return cfg.TypeOf(c.CardTypes, labels, c.hasCategory(labels))
+}
+
+func hasLabel(labels []string, label string) bool {
+	if label == "" {
+		return false
+	}
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// setSchedule adds the optional dates to a card payload. Trello expects the
+// reminder in minutes before the due date.
+func setSchedule(payload map[string]string, schedule model.Schedule) {
+	if schedule.Due != nil {
+		payload["due"] = schedule.Due.Format(time.RFC3339)
+	}
+	if schedule.Start != nil {
+		payload["start"] = schedule.Start.Format(time.RFC3339)
+	}
+	if schedule.Reminder > 0 {
+		payload["dueReminder"] = strconv.Itoa(int(schedule.Reminder.Minutes()))
+	}
+}
+
+func (c card) toCard() *model.Card {
+	return &model.Card{
+		Id:          c.Id,
+		Title:       c.Name,
+		Description: c.Desc,
+		Url:         c.Url,
+		BoardId:     c.IdBoard,
+		ListId:      c.IdList,
+		Labels:      c.IdLabels,
+		Members:     c.IdMembers,
+		Due:         c.Due,
+		Start:       c.Start,
+		Closed:      c.Closed,
+	}
+}
+
+func (a action) toComment() *model.Comment {
+	return &model.Comment{
+		Id:        a.Id,
+		Text:      a.Data.Text,
+		Author:    a.MemberCreator.Username,
+		CreatedAt: a.Date,
+	}
+}
+
+func (a attachment) toAttachment() *model.Attachment {
+	return &model.Attachment{
+		Id:       a.Id,
+		Name:     a.Name,
+		Url:      a.Url,
+		Bytes:    a.Bytes,
+		MimeType: a.MimeType,
+	}
+}
+
+func (c checklist) toChecklist() *model.Checklist {
+	items := c.CheckItems
+	if items == nil {
+		items = []model.CheckItem{}
+	}
+	return &model.Checklist{
+		Id:    c.Id,
+		Name:  c.Name,
+		Items: items,
 	}
-	return categoryToLabel[category]
 }
@@ -1,10 +1,13 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bmatiasx/go-task-mgr/internal/cfg"
 	"github.com/bmatiasx/go-task-mgr/internal/model"
@@ -17,7 +20,7 @@ func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return f(req), nil
 }
 
-func TestClient_CreateIssue(t *testing.T) {
+func TestClient_CreateCard_Issue(t *testing.T) {
 
 	issueJSON := `{
 	"id": "6423991687731e2e9e1fec60",
@@ -59,16 +62,17 @@ func TestClient_CreateIssue(t *testing.T) {
 		TestLabelId:        "13",
 	}
 
-	issue := model.Issue{
+	issue := model.NewCard{
 		Type:        "issue",
 		Title:       "CD player not working",
 		Description: "Change laser reader of CD player",
+		ListId:      "1",
 	}
 
 	c := New(config)
 	c.client = httpClient
 
-	card, err := c.CreateIssue(issue)
+	card, err := c.CreateCard(context.Background(), issue)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -79,7 +83,7 @@ func TestClient_CreateIssue(t *testing.T) {
 	assert.NotEmptyf(t, card.ListId, "ListId is empty")
 }
 
-func TestClient_CreateBug(t *testing.T) {
+func TestClient_CreateCard_Bug(t *testing.T) {
 
 	bugJSON := `{
 	"id": "6423991687731e2e9e1fec60",
@@ -121,15 +125,19 @@ func TestClient_CreateBug(t *testing.T) {
 		TestLabelId:        "13",
 	}
 
-	bug := model.Bug{
-		Type:        "Fuel indicator malfunction",
+	bug := model.NewCard{
+		Type:        "bug",
+		Title:       "BUG-0001: Fuel level indicator not working properly",
 		Description: "Fuel level indicator not working properly",
+		ListId:      "2",
+		Labels:      []string{"10"},
+		Severity:    model.SeverityCritical,
 	}
 
 	c := New(config)
 	c.client = httpClient
 
-	card, err := c.CreateBug(bug)
+	card, err := c.CreateCard(context.Background(), bug)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -140,7 +148,44 @@ func TestClient_CreateBug(t *testing.T) {
 	assert.NotEmptyf(t, card.ListId, "ListId is empty")
 }
 
-func TestClient_CreateTask(t *testing.T) {
+func TestClient_CreateCard_Severity(t *testing.T) {
+	reqString := "https://example.com/1/cards?idList=1&key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"name": "bug-low-3", "desc": "Scratched cover", "idLabels": "10,22,31"}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": "1", "idList": "1"}`)),
+		}
+	})}
+
+	config := testConfig
+	config.SeverityLabelIds = map[string]string{"critical": "20", "low": "22"}
+	config.PriorityLabelIds = map[string]string{"low": "31"}
+	c := New(config)
+	c.client = httpClient
+
+	_, err := c.CreateCard(context.Background(), model.NewCard{
+		Type:        "bug",
+		Title:       "bug-low-3",
+		Description: "Scratched cover",
+		ListId:      "1",
+		Labels:      []string{"10"},
+		Severity:    model.SeverityLow,
+		Priority:    model.PriorityLow,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient_CreateCard_Task(t *testing.T) {
 
 	bugJSON := `{
 	"id": "6423991687731e2e9e1fec60",
@@ -182,16 +227,18 @@ func TestClient_CreateTask(t *testing.T) {
 		TestLabelId:        "13",
 	}
 
-	task := model.Task{
-		Type:     "Maintenance",
-		Title:    "Keys cleaning",
-		Category: "Program a keyboard cleaning every week",
+	task := model.NewCard{
+		Type:        "task",
+		Title:       "Keys cleaning",
+		Description: "Belongs to category Maintenance",
+		ListId:      "1",
+		Category:    "Maintenance",
 	}
 
 	c := New(config)
 	c.client = httpClient
 
-	card, err := c.CreateTask(task)
+	card, err := c.CreateCard(context.Background(), task)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -201,3 +248,368 @@ func TestClient_CreateTask(t *testing.T) {
 	assert.NotEmptyf(t, card.BoardId, "BoardId is empty")
 	assert.NotEmptyf(t, card.ListId, "ListId is empty")
 }
+
+var testConfig = cfg.Config{
+	URL:                "https://example.com",
+	APIKey:             "ABC123",
+	Token:              "123QWE",
+	AppPort:            ":3000",
+	ToDoListId:         "1",
+	DoingListId:        "2",
+	BugLabelId:         "10",
+	MaintenanceLabelId: "11",
+	ResearchLabelId:    "12",
+	TestLabelId:        "13",
+}
+
+func TestClient_UpdateCard(t *testing.T) {
+
+	cardJSON := `{
+	"id": "6423991687731e2e9e1fec60",
+	"name": "Keys cleaning",
+	"idBoard": "63bdd2e8fdf46c026cf9aff2",
+	"idList": "2",
+	"url": "https://example.com/c/gpHVOuR7/66-keys-cleaning"
+	}`
+
+	reqString := "https://example.com/1/cards/6423991687731e2e9e1fec60?key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+		if req.Method != http.MethodPut {
+			t.Errorf("expected request method to be PUT, got %s", req.Method)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"name": "Keys cleaning", "idList": "2"}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(cardJSON)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	card, err := c.UpdateCard(context.Background(), "6423991687731e2e9e1fec60", model.CardUpdate{Title: "Keys cleaning", ListId: "2"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "Keys cleaning", card.Title)
+	assert.Equal(t, "2", card.ListId)
+}
+
+func TestClient_DeleteCard(t *testing.T) {
+
+	reqString := "https://example.com/1/cards/6423991687731e2e9e1fec60?key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+		if req.Method != http.MethodDelete {
+			t.Errorf("expected request method to be DELETE, got %s", req.Method)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"limits": {}}`)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	err := c.DeleteCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	assert.NoError(t, err)
+}
+
+func TestClient_GetCard_NotFound(t *testing.T) {
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet {
+			t.Errorf("expected request method to be GET, got %s", req.Method)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       ioutil.NopCloser(strings.NewReader("The requested resource was not found.")),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestClient_ArchiveCard(t *testing.T) {
+
+	reqString := "https://example.com/1/cards/6423991687731e2e9e1fec60?key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"closed": true}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": "6423991687731e2e9e1fec60", "closed": true}`)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	card, err := c.ArchiveCard(context.Background(), "6423991687731e2e9e1fec60")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.True(t, card.Closed)
+}
+
+func TestClient_ListCards(t *testing.T) {
+
+	searchJSON := `{"cards": [
+	{"id": "a1", "name": "bug-critical-1", "idList": "2", "idLabels": ["10"]},
+	{"id": "b2", "name": "Fuel pump check", "idList": "1", "idLabels": ["11"]},
+	{"id": "c3", "name": "Fuel gauge", "idList": "2", "idLabels": []},
+	{"id": "d4", "name": "bug-critical-2", "idList": "2", "idLabels": ["10"], "closed": true}
+	]}`
+
+	reqString := "https://example.com/1/search?query=fuel+gauge&idBoards=B1&modelTypes=cards&cards_limit=1000" +
+		"&card_fields=name,desc,url,idBoard,idList,idLabels,idMembers,due,start,closed&key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(searchJSON)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+
+	cards, err := c.ListCards(context.Background(), model.CardQuery{Text: "fuel gauge", ListId: "2", Type: "bug"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, cards, 1)
+	assert.Equal(t, "a1", cards[0].Id)
+}
+
+func TestClient_ListComments(t *testing.T) {
+
+	actionsJSON := `[
+	{"id": "a2", "date": "2023-04-02T10:00:00.000Z", "data": {"text": "Fix deployed"}, "memberCreator": {"username": "ci-bot"}},
+	{"id": "a1", "date": "2023-04-01T10:00:00.000Z", "data": {"text": "Looking into it"}, "memberCreator": {"username": "jdoe"}}
+	]`
+
+	reqString := "https://example.com/1/cards/6423991687731e2e9e1fec60/actions?filter=commentCard&key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(actionsJSON)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	comments, err := c.ListComments(context.Background(), "6423991687731e2e9e1fec60")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "Fix deployed", comments[0].Text)
+	assert.Equal(t, "ci-bot", comments[0].Author)
+	assert.False(t, comments[0].CreatedAt.IsZero())
+}
+
+func TestClient_AddAttachment(t *testing.T) {
+
+	reqString := "https://example.com/1/cards/6423991687731e2e9e1fec60/attachments?key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() != reqString {
+			t.Errorf("expected request to be %s, got %s", reqString, req.URL.String())
+		}
+		if err := req.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("expected a multipart request: %v", err)
+		}
+		file, header, err := req.FormFile("file")
+		if err != nil {
+			t.Fatalf("expected a file part: %v", err)
+		}
+		content, _ := ioutil.ReadAll(file)
+		assert.Equal(t, "engine.log", header.Filename)
+		assert.Equal(t, "text/plain", header.Header.Get("Content-Type"))
+		assert.Equal(t, "pressure low", string(content))
+		assert.Equal(t, "engine.log", req.FormValue("name"))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{"id": "att1", "name": "engine.log", "bytes": 12,
+				"mimeType": "text/plain", "url": "https://example.com/attachments/engine.log"}`)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	attachment, err := c.AddAttachment(context.Background(), "6423991687731e2e9e1fec60", model.File{
+		Name:     "engine.log",
+		MimeType: "text/plain",
+		Content:  strings.NewReader("pressure low"),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "att1", attachment.Id)
+	assert.Equal(t, int64(12), attachment.Bytes)
+}
+
+func TestClient_AddChecklist(t *testing.T) {
+
+	requests := []string{}
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+
+		body := `{"id": "cl1", "name": "Checklist", "checkItems": []}`
+		if strings.HasSuffix(req.URL.Path, "/checkItems") {
+			b, _ := ioutil.ReadAll(req.Body)
+			var item map[string]string
+			_ = json.Unmarshal(b, &item)
+			body = `{"id": "ci-` + item["name"] + `", "name": "` + item["name"] + `", "state": "incomplete"}`
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	checklist, err := c.AddChecklist(context.Background(), "6423991687731e2e9e1fec60", "Checklist", []string{"drain", "refill"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, []string{
+		"POST /1/checklists",
+		"POST /1/checklists/cl1/checkItems",
+		"POST /1/checklists/cl1/checkItems",
+	}, requests)
+	assert.Equal(t, "cl1", checklist.Id)
+	assert.Equal(t, []model.CheckItem{
+		{Id: "ci-drain", Name: "drain", State: "incomplete"},
+		{Id: "ci-refill", Name: "refill", State: "incomplete"},
+	}, checklist.Items)
+}
+
+func TestClient_CreateCard_Schedule(t *testing.T) {
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{
+			"name": "No pilot mode",
+			"desc": "Enable no pilot mode",
+			"due": "2023-04-10T15:00:00Z",
+			"start": "2023-04-04T09:00:00Z",
+			"dueReminder": "1440"
+		}`, string(body))
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": "1", "due": "2023-04-10T15:00:00.000Z"}`)),
+		}
+	})}
+
+	c := New(testConfig)
+	c.client = httpClient
+
+	due := time.Date(2023, 4, 10, 15, 0, 0, 0, time.UTC)
+	start := time.Date(2023, 4, 4, 9, 0, 0, 0, time.UTC)
+	card, err := c.CreateCard(context.Background(), model.NewCard{
+		Type:        "issue",
+		Title:       "No pilot mode",
+		Description: "Enable no pilot mode",
+		Schedule:    model.Schedule{Due: &due, Start: &start, Reminder: 24 * time.Hour},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.True(t, due.Equal(*card.Due))
+}
+
+// blockingTransport waits until the request is cancelled, like a Trello
+// call that never answers.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func TestClient_GetCard_Deadline(t *testing.T) {
+	c := New(testConfig)
+	c.client = &http.Client{Transport: blockingTransport{}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetCard(ctx, "6423991687731e2e9e1fec60")
+
+	assert.ErrorIs(t, err, model.ErrTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_GetCard_Cancelled(t *testing.T) {
+	c := New(testConfig)
+	c.client = &http.Client{Transport: blockingTransport{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.GetCard(ctx, "6423991687731e2e9e1fec60")
+
+	assert.ErrorIs(t, err, model.ErrTimeout)
+	assert.Contains(t, err.Error(), "context canceled")
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const fieldsTTL = 10 * time.Minute
+
+// customField is the Trello representation of a custom field definition.
+// Options are only set for list fields.
+type customField struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Options []struct {
+		Id    string `json:"id"`
+		Value struct {
+			Text string `json:"text"`
+		} `json:"value"`
+	} `json:"options"`
+}
+
+// customFieldItem is the value of a custom field on a card. List fields take
+// the id of an option instead of a value.
+type customFieldItem struct {
+	IdCustomField string            `json:"idCustomField"`
+	Value         map[string]string `json:"value,omitempty"`
+	IdValue       string            `json:"idValue,omitempty"`
+}
+
+// fieldCache maps the lowercase names of the board custom fields to their
+// definitions. The lock only guards the map, Trello is called without
+// holding it.
+type fieldCache struct {
+	mu      sync.Mutex
+	fetched time.Time
+	fields  map[string]customField
+}
+
+func (f *fieldCache) stale() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Since(f.fetched) > fieldsTTL
+}
+
+func (f *fieldCache) field(name string) (customField, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	field, ok := f.fields[strings.ToLower(strings.TrimSpace(name))]
+	return field, ok
+}
+
+// set replaces the cached fields with those of the board.
+func (f *fieldCache) set(boardFields []customField) {
+	fields := make(map[string]customField, len(boardFields))
+	for _, bf := range boardFields {
+		fields[strings.ToLower(bf.Name)] = bf
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fields, f.fetched = fields, time.Now()
+}
+
+// ValidateFields checks the fields are custom fields of the board and their
+// values fit the field types.
+func (c *Client) ValidateFields(ctx context.Context, fields map[string]interface{}) error {
+	_, err := c.fieldItems(ctx, fields)
+	return err
+}
+
+// SetFields sets the custom fields of the card in a single request.
+func (c *Client) SetFields(ctx context.Context, cardId string, fields map[string]interface{}) error {
+	items, err := c.fieldItems(ctx, fields)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/%s/customFields?key=%s&token=%s", c.URL, cardsPath, cardId, c.APIKey, c.Token)
+	log.Printf("setting %d custom fields of card %s with Trello API", len(items), cardId)
+
+	request := map[string][]customFieldItem{"customFieldItems": items}
+	err = c.call(ctx, request, nil, http.MethodPut, url)
+	if err != nil {
+		log.Printf("error while setting the custom fields of card %s", cardId)
+		return fmt.Errorf("error: %w", err)
+	}
+	return nil
+}
+
+// fieldItems converts the fields to custom field items, sorted by name so
+// requests are stable. The definitions are refreshed once when a field is
+// not found.
+func (c *Client) fieldItems(ctx context.Context, fields map[string]interface{}) ([]customFieldItem, error) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	refreshed := false
+	if c.fields.stale() {
+		if err := c.fetchFields(ctx); err != nil {
+			return nil, err
+		}
+		refreshed = true
+	}
+
+	items := make([]customFieldItem, 0, len(names))
+	for _, name := range names {
+		field, ok := c.fields.field(name)
+		if !ok && !refreshed {
+			if err := c.fetchFields(ctx); err != nil {
+				return nil, err
+			}
+			refreshed = true
+			field, ok = c.fields.field(name)
+		}
+		if !ok {
+			log.Printf("unknown custom field: %s", name)
+			return nil, fmt.Errorf("unknown custom field %q, it must be defined in the board: %w", name, model.ErrInvalidRequest)
+		}
+		item, err := field.item(fields[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of custom field %q, %v: %w", name, err, model.ErrInvalidRequest)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (c *Client) fetchFields(ctx context.Context) error {
+	url := fmt.Sprintf("%s/%s/%s/customFields?key=%s&token=%s", c.URL, boardsPath, c.BoardId, c.APIKey, c.Token)
+	log.Printf("getting the custom fields of board %s from Trello API", c.BoardId)
+
+	var fieldsResp []customField
+
+	err := c.call(ctx, nil, &fieldsResp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting the board custom fields")
+		return fmt.Errorf("error: %w", err)
+	}
+
+	c.fields.set(fieldsResp)
+	return nil
+}
+
+// item converts a request value to the value of the field type. Numbers,
+// dates and checkboxes are also accepted as strings.
+func (f customField) item(value interface{}) (customFieldItem, error) {
+	item := customFieldItem{IdCustomField: f.Id}
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = strings.TrimSpace(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		return item, fmt.Errorf("expected a string, number or boolean")
+	}
+
+	switch f.Type {
+	case "text":
+		item.Value = map[string]string{"text": text}
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return item, fmt.Errorf("expected a number")
+		}
+		item.Value = map[string]string{"number": strconv.FormatFloat(n, 'f', -1, 64)}
+	case "date":
+		date, err := parseDate(text)
+		if err != nil {
+			return item, fmt.Errorf("expected a date")
+		}
+		item.Value = map[string]string{"date": date.Format(time.RFC3339)}
+	case "checkbox":
+		checked, err := strconv.ParseBool(text)
+		if err != nil {
+			return item, fmt.Errorf("expected true or false")
+		}
+		item.Value = map[string]string{"checked": strconv.FormatBool(checked)}
+	case "list":
+		for _, option := range f.Options {
+			if strings.EqualFold(option.Value.Text, text) {
+				item.IdValue = option.Id
+				return item, nil
+			}
+		}
+		return item, fmt.Errorf("%q is not an option", text)
+	default:
+		return item, fmt.Errorf("unsupported field type %q", f.Type)
+	}
+	return item, nil
+}
+
+// parseDate accepts RFC 3339 timestamps and plain dates, taken as UTC.
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
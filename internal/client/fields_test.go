@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const customFieldsJSON = `[
+	{"id": "F1", "name": "Customer", "type": "text"},
+	{"id": "F2", "name": "Component", "type": "list", "options": [
+		{"id": "O1", "value": {"text": "Dashboard"}},
+		{"id": "O2", "value": {"text": "Engine"}}
+	]},
+	{"id": "F3", "name": "Units", "type": "number"},
+	{"id": "F4", "name": "Reproducible", "type": "checkbox"}
+]`
+
+func TestClient_SetFields(t *testing.T) {
+	fieldsReq := "https://example.com/1/boards/B1/customFields?key=ABC123&token=123QWE"
+	setReq := "https://example.com/1/cards/C1/customFields?key=ABC123&token=123QWE"
+	calls := map[string]int{}
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		calls[req.URL.String()]++
+		if req.URL.String() == fieldsReq {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(customFieldsJSON)),
+			}
+		}
+		assert.Equal(t, setReq, req.URL.String())
+		assert.Equal(t, http.MethodPut, req.Method)
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"customFieldItems": [
+			{"idCustomField": "F1", "value": {"text": "ACME"}},
+			{"idCustomField": "F2", "idValue": "O1"},
+			{"idCustomField": "F4", "value": {"checked": "true"}},
+			{"idCustomField": "F3", "value": {"number": "3"}}
+		]}`, string(body))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[]`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+
+	fields := map[string]interface{}{"component": "dashboard", "Customer": "ACME", "reproducible": true, "units": float64(3)}
+	assert.NoError(t, c.ValidateFields(context.Background(), fields))
+	assert.NoError(t, c.SetFields(context.Background(), "C1", fields))
+
+	// The field definitions are cached
+	assert.Equal(t, 1, calls[fieldsReq])
+	assert.Equal(t, 1, calls[setReq])
+}
+
+func TestClient_ValidateFields_Invalid(t *testing.T) {
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(customFieldsJSON)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+
+	for _, fields := range []map[string]interface{}{
+		{"owner": "dana"},
+		{"component": "Wheels"},
+		{"units": "three"},
+		{"reproducible": "sometimes"},
+		{"customer": []interface{}{"ACME"}},
+	} {
+		err := c.ValidateFields(context.Background(), fields)
+		assert.True(t, errors.Is(err, model.ErrInvalidRequest), "%v: %v", fields, err)
+	}
+}
+
+func TestClient_ValidateFields_NotBlocked(t *testing.T) {
+	fetching, release := make(chan struct{}), make(chan struct{})
+
+	// Mock http client response, the refresh of the fields hangs until released
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		close(fetching)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "F5", "name": "Owner", "type": "text"}]`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+	c.fields.set([]customField{{Id: "F1", Name: "Customer", Type: "text"}})
+
+	done := make(chan error)
+	go func() {
+		done <- c.ValidateFields(context.Background(), map[string]interface{}{"owner": "dana"})
+	}()
+	<-fetching
+
+	// Cached fields validate while the refresh is still waiting for Trello
+	validated := make(chan error)
+	go func() {
+		validated <- c.ValidateFields(context.Background(), map[string]interface{}{"customer": "ACME"})
+	}()
+	select {
+	case err := <-validated:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("validating a cached field waited for the refresh")
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+)
+
+// label is the Trello representation of a board label.
+type label struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ResolveLabels looks up the ids of the categories configured with a label
+// name instead of an id. It is called once at startup.
+func (c *Client) ResolveLabels(ctx context.Context) error {
+	pending := false
+	for _, category := range c.Categories {
+		if category.Label == "" && category.LabelName != "" {
+			pending = true
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	url := fmt.Sprintf("%s/%s/%s/labels?fields=name&key=%s&token=%s", c.URL, boardsPath, c.BoardId, c.APIKey, c.Token)
+	log.Printf("getting the labels of board %s from Trello API", c.BoardId)
+
+	var labelsResp []label
+
+	err := c.call(ctx, nil, &labelsResp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting the board labels")
+		return fmt.Errorf("error: %w", err)
+	}
+
+	ids := make(map[string]string, len(labelsResp))
+	for _, l := range labelsResp {
+		ids[strings.ToLower(l.Name)] = l.Id
+	}
+	for i, category := range c.Categories {
+		if category.Label != "" || category.LabelName == "" {
+			continue
+		}
+		id, ok := ids[strings.ToLower(category.LabelName)]
+		if !ok {
+			return fmt.Errorf("label %q of category %q not found in board %s", category.LabelName, category.Name, c.BoardId)
+		}
+		c.Categories[i].Label = id
+	}
+	return nil
+}
+
+// setLabel returns the label id of the category, matched by name or alias.
+func (c *Client) setLabel(category string) string {
+	v, _ := cfg.FindCategory(c.Categories, category)
+	return v.Label
+}
+
+// hasCategory tells whether any of the labels is a category label.
+func (c *Client) hasCategory(labels []string) bool {
+	for _, v := range c.Categories {
+		if hasLabel(labels, v.Label) {
+			return true
+		}
+	}
+	return false
+}
+
+// categories copies the configured categories, since their label names are
+// resolved in place.
+func categories(conf cfg.Config) []cfg.Category {
+	return append([]cfg.Category(nil), conf.CategoriesFor("trello")...)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ResolveLabels(t *testing.T) {
+	labelsReq := "https://example.com/1/boards/B1/labels?fields=name&key=ABC123&token=123QWE"
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.String() == labelsReq {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: ioutil.NopCloser(strings.NewReader(`[
+					{"id": "L1", "name": "Security"},
+					{"id": "L2", "name": "ops"}
+				]`)),
+			}
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"name": "Rotate keys", "desc": "", "idLabels": "L1"}`, string(body))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": "1", "idLabels": ["L1"]}`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	config.Categories = []cfg.Category{
+		{Name: "Maintenance", Label: "11"},
+		{Name: "Security", Aliases: []string{"sec"}, LabelName: "security"},
+	}
+	c := New(config)
+	c.client = httpClient
+
+	err := c.ResolveLabels(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "L1", c.setLabel("SEC"))
+	card, err := c.CreateCard(context.Background(), model.NewCard{Title: "Rotate keys", ListId: "1", Category: "security"})
+	assert.NoError(t, err)
+	assert.Equal(t, "task", c.cardType(card.Labels))
+}
+
+func TestClient_ResolveLabels_Unknown(t *testing.T) {
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "L2", "name": "ops"}]`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	config.Categories = []cfg.Category{{Name: "Security", LabelName: "security"}}
+	c := New(config)
+	c.client = httpClient
+
+	err := c.ResolveLabels(context.Background())
+
+	assert.EqualError(t, err, `label "security" of category "Security" not found in board B1`)
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+// limiterMetrics are published in /debug/vars: the requests let through,
+// those that had to wait and for how long in total, and those rejected
+// because the wait was too long.
+var limiterMetrics = expvar.NewMap("trello_rate_limiter")
+
+// limiter is a token bucket shared by all the calls of the client, so bulk
+// work stays under the Trello quota of requests per token instead of
+// running into 429s. It holds up to burst tokens and refills them at rate
+// per second. Callers queue for a token for up to maxWait.
+type limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	maxWait time.Duration
+	now     func() time.Time
+	metrics *expvar.Map
+}
+
+// newLimiter allows limit requests per interval, or is nil, allowing every
+// request, when the limit is not set.
+func newLimiter(limit int, interval time.Duration, maxWait time.Duration) *limiter {
+	if limit <= 0 || interval <= 0 {
+		return nil
+	}
+	return &limiter{
+		rate:    float64(limit) / interval.Seconds(),
+		burst:   float64(limit),
+		tokens:  float64(limit),
+		maxWait: maxWait,
+		now:     time.Now,
+		metrics: limiterMetrics,
+	}
+}
+
+// Wait takes a token, waiting for one when the bucket is empty. It fails
+// without waiting when the token would come after maxWait or after the
+// deadline of the context.
+func (l *limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	l.refill(now)
+	var delay time.Duration
+	if l.tokens < 1 {
+		delay = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	}
+	deadline, ok := ctx.Deadline()
+	if delay > l.maxWait || (ok && deadline.Before(now.Add(delay))) {
+		l.mu.Unlock()
+		l.metrics.Add("rejected", 1)
+		return fmt.Errorf("Trello rate limit reached, next request allowed in %v: %w", delay.Round(time.Millisecond), model.ErrUnavailable)
+	}
+	// The token is taken now, so the callers queue in order.
+	l.tokens--
+	l.mu.Unlock()
+
+	l.metrics.Add("allowed", 1)
+	if delay <= 0 {
+		return nil
+	}
+	l.metrics.Add("throttled", 1)
+	l.metrics.Add("wait_ms", delay.Milliseconds())
+	if err := wait(ctx, delay); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return model.TransportError(err)
+	}
+	return nil
+}
+
+// drain empties the bucket after Trello answered 429, as its quota was used
+// by someone else sharing the token.
+func (l *limiter) drain() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.now())
+	if l.tokens > 0 {
+		l.tokens = 0
+	}
+}
+
+func (l *limiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
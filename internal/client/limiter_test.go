@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(limit int, interval time.Duration, maxWait time.Duration) *limiter {
+	l := newLimiter(limit, interval, maxWait)
+	l.metrics = new(expvar.Map).Init()
+	return l
+}
+
+func metric(l *limiter, name string) int64 {
+	v, ok := l.metrics.Get(name).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestLimiter_Burst(t *testing.T) {
+	l := newTestLimiter(3, time.Hour, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Wait(context.Background()))
+	}
+	err := l.Wait(context.Background())
+
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Equal(t, int64(3), metric(l, "allowed"))
+	assert.Equal(t, int64(1), metric(l, "rejected"))
+}
+
+func TestLimiter_Queue(t *testing.T) {
+	// Given an empty bucket refilled every 20ms
+	l := newTestLimiter(1, 20*time.Millisecond, time.Second)
+	assert.NoError(t, l.Wait(context.Background()))
+
+	// When two more requests come in
+	start := time.Now()
+	assert.NoError(t, l.Wait(context.Background()))
+	assert.NoError(t, l.Wait(context.Background()))
+
+	// Then they wait for their turn one after the other
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	assert.Equal(t, int64(2), metric(l, "throttled"))
+	assert.Greater(t, metric(l, "wait_ms"), int64(0))
+}
+
+func TestLimiter_Deadline(t *testing.T) {
+	l := newTestLimiter(1, time.Second, 5*time.Second)
+	assert.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// The next token comes after the deadline, so it fails right away
+	start := time.Now()
+	err := l.Wait(ctx)
+
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Less(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestLimiter_Refill(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(10, 10*time.Second, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Wait(context.Background()))
+	}
+	assert.Error(t, l.Wait(context.Background()))
+
+	// One token per second comes back, never more than the burst
+	now = now.Add(2 * time.Second)
+	assert.NoError(t, l.Wait(context.Background()))
+	assert.NoError(t, l.Wait(context.Background()))
+	assert.Error(t, l.Wait(context.Background()))
+
+	now = now.Add(time.Hour)
+	l.drain()
+	assert.Error(t, l.Wait(context.Background()))
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	l := newLimiter(0, 10*time.Second, 0)
+
+	assert.Nil(t, l)
+	assert.NoError(t, l.Wait(context.Background()))
+}
+
+func TestClient_RateLimited(t *testing.T) {
+	attempts := 0
+	c := New(testConfig)
+	c.client = &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		attempts++
+		return response(http.StatusOK, `{"id": "6423991687731e2e9e1fec60"}`)
+	})}
+	c.limiter = newTestLimiter(2, time.Hour, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+		assert.NoError(t, err)
+	}
+	_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	// The third call is not sent to Trello
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const (
+	membersTTL  = 10 * time.Minute
+	membersPath = "1/search/members"
+)
+
+// member is the Trello representation of a board member. The email is only
+// returned when the token owner is allowed to see it.
+type member struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// memberCache maps lowercase usernames and emails of the board members to
+// their ids, so assignees are not resolved against Trello on every card. The
+// lock only guards the maps, Trello is called without holding it.
+type memberCache struct {
+	mu      sync.Mutex
+	fetched time.Time
+	ids     map[string]string
+	members map[string]bool
+}
+
+func (m *memberCache) stale() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Since(m.fetched) > membersTTL
+}
+
+func (m *memberCache) id(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.ids[key]
+	return id, ok
+}
+
+func (m *memberCache) isMember(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.members[id]
+}
+
+// add caches the id of a member found by email.
+func (m *memberCache) add(key string, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ids != nil {
+		m.ids[key] = id
+	}
+}
+
+// set replaces the cached members with those of the board.
+func (m *memberCache) set(boardMembers []member) {
+	ids := map[string]string{}
+	members := map[string]bool{}
+	for _, bm := range boardMembers {
+		members[bm.Id] = true
+		ids[memberKey(bm.Username)] = bm.Id
+		if bm.Email != "" {
+			ids[memberKey(bm.Email)] = bm.Id
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ids, m.members, m.fetched = ids, members, time.Now()
+}
+
+// setMembers resolves the assignees and adds them to the card payload.
+func (c *Client) setMembers(ctx context.Context, payload map[string]string, assignees []string) error {
+	ids, err := c.resolveMembers(ctx, assignees)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		payload["idMembers"] = strings.Join(ids, ",")
+	}
+	return nil
+}
+
+// resolveMembers returns the member ids of the assignees, given as usernames
+// or emails. The cache is refreshed once when an assignee is not found.
+func (c *Client) resolveMembers(ctx context.Context, assignees []string) ([]string, error) {
+	if len(assignees) == 0 {
+		return nil, nil
+	}
+
+	refreshed := false
+	if c.members.stale() {
+		if err := c.fetchMembers(ctx); err != nil {
+			return nil, err
+		}
+		refreshed = true
+	}
+
+	for {
+		ids, unknown, err := c.lookupMembers(ctx, assignees)
+		if err != nil {
+			return nil, err
+		}
+		if len(unknown) == 0 {
+			return ids, nil
+		}
+		if refreshed {
+			log.Printf("unknown assignees: %s", strings.Join(unknown, ", "))
+			return nil, fmt.Errorf("unknown assignees %s, they must be members of the board: %w",
+				strings.Join(unknown, ", "), model.ErrInvalidRequest)
+		}
+		if err := c.fetchMembers(ctx); err != nil {
+			return nil, err
+		}
+		refreshed = true
+	}
+}
+
+func (c *Client) lookupMembers(ctx context.Context, assignees []string) ([]string, []string, error) {
+	var ids, unknown []string
+	for _, assignee := range assignees {
+		key := memberKey(assignee)
+		id, ok := c.members.id(key)
+		if !ok && strings.Contains(key, "@") {
+			var err error
+			if id, ok, err = c.searchMember(ctx, key); err != nil {
+				return nil, nil, err
+			}
+		}
+		if !ok {
+			unknown = append(unknown, assignee)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, unknown, nil
+}
+
+func (c *Client) fetchMembers(ctx context.Context) error {
+	url := fmt.Sprintf("%s/%s/%s/members?fields=username,email&key=%s&token=%s", c.URL, boardsPath, c.BoardId, c.APIKey, c.Token)
+	log.Printf("getting the members of board %s from Trello API", c.BoardId)
+
+	var membersResp []member
+
+	err := c.call(ctx, nil, &membersResp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting the board members")
+		return fmt.Errorf("error: %w", err)
+	}
+
+	c.members.set(membersResp)
+	return nil
+}
+
+// searchMember looks the email up with the search API, since Trello hides the
+// emails of most board members. Only members of the board are accepted.
+func (c *Client) searchMember(ctx context.Context, email string) (string, bool, error) {
+	endpoint := fmt.Sprintf("%s/%s?query=%s&idBoard=%s&limit=1&key=%s&token=%s",
+		c.URL, membersPath, url.QueryEscape(email), c.BoardId, c.APIKey, c.Token)
+	log.Printf("searching member %s with Trello API", email)
+
+	var membersResp []member
+
+	err := c.call(ctx, nil, &membersResp, http.MethodGet, endpoint)
+	if err != nil {
+		log.Printf("error while searching member %s", email)
+		return "", false, fmt.Errorf("error: %w", err)
+	}
+
+	for _, m := range membersResp {
+		if c.members.isMember(m.Id) {
+			c.members.add(email, m.Id)
+			return m.Id, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func memberKey(assignee string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(assignee), "@"))
+}
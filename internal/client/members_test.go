@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_CreateCard_Assignees(t *testing.T) {
+	membersReq := "https://example.com/1/boards/B1/members?fields=username,email&key=ABC123&token=123QWE"
+	searchReq := "https://example.com/1/search/members?query=dana%40example.com&idBoard=B1&limit=1&key=ABC123&token=123QWE"
+	calls := map[string]int{}
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		calls[req.URL.String()]++
+		switch req.URL.String() {
+		case membersReq:
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: ioutil.NopCloser(strings.NewReader(`[
+					{"id": "M1", "username": "alex"},
+					{"id": "M2", "username": "dana"}
+				]`)),
+			}
+		case searchReq:
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "M2", "username": "dana"}]`)),
+			}
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.JSONEq(t, `{"name": "No pilot mode", "desc": "", "idMembers": "M1,M2"}`, string(body))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`{"id": "1", "idMembers": ["M1", "M2"]}`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+
+	for i := 0; i < 2; i++ {
+		card, err := c.CreateCard(context.Background(), model.NewCard{Title: "No pilot mode", Assignees: []string{"@Alex", "dana@example.com"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, []string{"M1", "M2"}, card.Members)
+	}
+
+	// The board members are cached, and so are the emails found by search
+	assert.Equal(t, 1, calls[membersReq])
+	assert.Equal(t, 1, calls[searchReq])
+}
+
+func TestClient_CreateCard_UnknownAssignee(t *testing.T) {
+
+	// Mock http client response
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL.String())
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "M1", "username": "alex"}]`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+
+	_, err := c.CreateCard(context.Background(), model.NewCard{Title: "No pilot mode", Assignees: []string{"alex", "robin"}})
+
+	assert.True(t, errors.Is(err, model.ErrInvalidRequest))
+	assert.Contains(t, err.Error(), "robin")
+}
+
+func TestClient_ResolveMembers_NotBlocked(t *testing.T) {
+	searching, release := make(chan struct{}), make(chan struct{})
+
+	// Mock http client response, the member search hangs until released
+	httpClient := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		close(searching)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(`[{"id": "M2", "username": "dana"}]`)),
+		}
+	})}
+
+	config := testConfig
+	config.BoardId = "B1"
+	c := New(config)
+	c.client = httpClient
+	c.members.set([]member{{Id: "M1", Username: "alex"}, {Id: "M2", Username: "dana"}})
+
+	done := make(chan error)
+	go func() {
+		_, err := c.resolveMembers(context.Background(), []string{"dana@example.com"})
+		done <- err
+	}()
+	<-searching
+
+	// Cached members resolve while the search is still waiting for Trello
+	resolved := make(chan []string)
+	go func() {
+		ids, _ := c.resolveMembers(context.Background(), []string{"alex"})
+		resolved <- ids
+	}()
+	select {
+	case ids := <-resolved:
+		assert.Equal(t, []string{"M1"}, ids)
+	case <-time.After(time.Second):
+		t.Fatal("resolving a cached member waited for the member search")
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+	id, ok := c.members.id("dana@example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "M2", id)
+}
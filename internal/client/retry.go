@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryPolicy retries the Trello calls that failed on rate limits, server
+// errors or network errors, waiting longer after each attempt. Trello has no
+// idempotency keys, so requests that create something are only retried when
+// Trello surely did not process them: rate limited or never sent.
+type retryPolicy struct {
+	// Retries is the number of attempts after the first one, zero disables
+	// retries.
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// next tells whether the failed attempt is retried and after how long. The
+// delay is the one Trello asked for in Retry-After, or the backoff otherwise.
+// It is not retried when the request deadline would pass while waiting.
+func (p retryPolicy) next(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt > p.Retries || !rewindable(req) {
+		return 0, false
+	}
+
+	var delay time.Duration
+	switch {
+	case err != nil:
+		if req.Context().Err() != nil || !(idempotent(req.Method) || isDialError(err)) {
+			return 0, false
+		}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		delay = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	case isServerError(resp.StatusCode) && idempotent(req.Method):
+		delay = retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	default:
+		return 0, false
+	}
+
+	if delay <= 0 {
+		delay = p.backoff(attempt)
+	}
+	if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+	return delay, true
+}
+
+// backoff doubles the delay on each attempt up to MaxDelay, with a random
+// half of it as jitter so clients do not retry in lockstep.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date.
+// It is zero when the header is missing or invalid.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// idempotent methods can be repeated without creating anything twice.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isServerError(code int) bool {
+	switch code {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isDialError tells whether the connection could not be opened, so the
+// request never reached Trello.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// wait sleeps for the delay, or until the context is done.
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rewindable requests have no body or one that can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind copies the request with a new body for another attempt.
+func rewind(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
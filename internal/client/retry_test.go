@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// transportFunc is a RoundTripFunc that can also fail to send the request.
+type transportFunc func(req *http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func response(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func newRetryClient(transport http.RoundTripper) *Client {
+	c := New(testConfig)
+	c.client = &http.Client{Transport: transport}
+	c.retry = retryPolicy{Retries: 3, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+	return c
+}
+
+func TestClient_Retry_ServerError(t *testing.T) {
+	attempts := 0
+	c := newRetryClient(transportFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			return response(http.StatusServiceUnavailable, ""), nil
+		}
+		return response(http.StatusOK, `{"id": "6423991687731e2e9e1fec60", "name": "Keys cleaning"}`), nil
+	}))
+
+	card, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Keys cleaning", card.Title)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestClient_Retry_GivesUp(t *testing.T) {
+	attempts := 0
+	c := newRetryClient(transportFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return response(http.StatusBadGateway, ""), nil
+	}))
+
+	_, err := c.GetCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestClient_Retry_ReplaysBody(t *testing.T) {
+	var bodies []string
+	c := newRetryClient(transportFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			return response(http.StatusInternalServerError, ""), nil
+		}
+		return response(http.StatusOK, `{"id": "6423991687731e2e9e1fec60", "closed": true}`), nil
+	}))
+
+	_, err := c.ArchiveCard(context.Background(), "6423991687731e2e9e1fec60")
+
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 2)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.JSONEq(t, `{"closed": true}`, bodies[1])
+}
+
+func TestClient_Retry_CreateCard(t *testing.T) {
+	for name, test := range map[string]struct {
+		first    func() (*http.Response, error)
+		attempts int
+	}{
+		"rate limited": {
+			first:    func() (*http.Response, error) { return response(http.StatusTooManyRequests, ""), nil },
+			attempts: 2,
+		},
+		"not connected": {
+			first: func() (*http.Response, error) {
+				return nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+			},
+			attempts: 2,
+		},
+		"server error": {
+			first:    func() (*http.Response, error) { return response(http.StatusInternalServerError, ""), nil },
+			attempts: 1,
+		},
+		"connection reset": {
+			first: func() (*http.Response, error) {
+				return nil, &net.OpError{Op: "read", Err: errors.New("connection reset")}
+			},
+			attempts: 1,
+		},
+	} {
+		attempts := 0
+		c := newRetryClient(transportFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return test.first()
+			}
+			return response(http.StatusOK, `{"id": "6423991687731e2e9e1fec60"}`), nil
+		}))
+
+		_, _ = c.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Keys cleaning", ListId: "1"})
+
+		// Cards are only created again when Trello surely did not create them
+		assert.Equal(t, test.attempts, attempts, name)
+	}
+}
+
+func TestClient_Retry_Deadline(t *testing.T) {
+	attempts := 0
+	c := newRetryClient(transportFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		resp := response(http.StatusTooManyRequests, "")
+		resp.Header.Set("Retry-After", "30")
+		return resp, nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := c.GetCard(ctx, "6423991687731e2e9e1fec60")
+
+	// Waiting 30 seconds would pass the deadline, so it fails right away
+	assert.ErrorIs(t, err, model.ErrUnavailable)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, delay := range map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Sat, 01 Apr 2023 12:00:30 GMT": 30 * time.Second,
+		"Sat, 01 Apr 2023 11:00:00 GMT": 0,
+	} {
+		assert.Equal(t, delay, retryAfter(value, now), value)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := retryPolicy{Retries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, max := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		5:  time.Second,
+		40: time.Second,
+	} {
+		delay := p.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, max/2)
+		assert.LessOrEqual(t, delay, max)
+	}
+}
@@ -1,12 +1,20 @@
 package controller
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bmatiasx/go-task-mgr/internal/model"
 	"github.com/bmatiasx/go-task-mgr/pkg/service"
@@ -14,11 +22,39 @@ import (
 
 const (
 	welcome = "/api/v1/welcome"
+	health  = "/api/v1/health"
 	task    = "/"
+	cards   = "/api/v1/cards"
+	schemas = "/api/v1/schemas"
 )
 
+// maxAttachmentSize is the largest file Trello accepts on free workspaces.
+const maxAttachmentSize = 10 << 20
+
+// maxBodySize limits the JSON request bodies.
+const maxBodySize = 1 << 20
+
+// timeoutHeader lets a request ask for a timeout other than the default,
+// as a duration like "30s" or in seconds.
+const timeoutHeader = "X-Request-Timeout"
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxTimeout = 60 * time.Second
+	// minTimeout leaves the tracker calls time to answer, so requests
+	// cannot make them fail on purpose.
+	minTimeout = time.Second
+)
+
+// taskFields maps the JSON names of the create request fields to their types.
+var taskFields = jsonFields(reflect.TypeOf(model.MasterTask{}))
+
 type TaskHandler struct {
 	service service.Servicer
+	// Timeout bounds the tracker calls of each request, MaxTimeout the
+	// timeouts requests may ask for. Zero values use the defaults.
+	Timeout    time.Duration
+	MaxTimeout time.Duration
 }
 
 func New(s service.Servicer) *TaskHandler {
@@ -26,38 +62,97 @@ func New(s service.Servicer) *TaskHandler {
 }
 
 func (h *TaskHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setCorrelationId(w, r)
+	defer recoverPanic(w, r)
+
+	timeout, err := h.timeout(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	if timeout < h.defaultTimeout() {
+		ctx = model.WithShortTimeout(ctx)
+	}
+	r = r.WithContext(ctx)
+
 	switch {
 	case r.Method == http.MethodGet && r.URL.Path == welcome:
 		h.HandleWelcome(w, r)
 		return
+	case r.Method == http.MethodGet && r.URL.Path == health:
+		h.HandleHealth(w, r)
+		return
 	case r.Method == http.MethodPost && r.URL.Path == task:
 		h.HandleTask(w, r)
 		return
+	case r.Method == http.MethodGet && r.URL.Path == cards:
+		h.HandleListCards(w, r)
+		return
+	case strings.HasPrefix(r.URL.Path, cards+"/"):
+		h.routeCard(w, r)
+		return
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, schemas+"/"):
+		h.HandleGetSchema(w, r)
+		return
 	default:
 		notFound(w, r)
 		return
 	}
 }
 
-func (h *TaskHandler) HandleWelcome(w http.ResponseWriter, r *http.Request) {
-	log.Printf("%s API was called", r.URL)
+func (h *TaskHandler) defaultTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return h.Timeout
+}
 
-	res := map[string]string{
-		"message": h.service.Welcome(),
+// timeout is the timeout the request asked for, at least minTimeout and
+// capped at MaxTimeout, or the default one.
+func (h *TaskHandler) timeout(r *http.Request) (time.Duration, error) {
+	timeout, max := h.defaultTimeout(), h.MaxTimeout
+	if max <= 0 {
+		max = defaultMaxTimeout
 	}
 
-	jsonRes, err := json.Marshal(res)
+	value := strings.TrimSpace(r.Header.Get(timeoutHeader))
+	if value == "" {
+		return timeout, nil
+	}
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		log.Fatalf("error marshaling json response. %s", err)
+		seconds, serr := strconv.ParseFloat(value, 64)
+		if serr != nil {
+			return 0, fmt.Errorf("header %s must be a duration like 30s or a number of seconds: %w", timeoutHeader, model.ErrInvalidRequest)
+		}
+		d = time.Duration(seconds * float64(time.Second))
 	}
+	if d < minTimeout {
+		return 0, fmt.Errorf("header %s must be at least %v: %w", timeoutHeader, minTimeout, model.ErrInvalidRequest)
+	}
+	if d > max {
+		d = max
+	}
+	return d, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+func (h *TaskHandler) HandleWelcome(w http.ResponseWriter, r *http.Request) {
+	log.Printf("%s API was called", r.URL)
 
-	_, err = w.Write(jsonRes)
-	if err != nil {
-		log.Fatalf("error writing json response. %s", err)
+	writeJSON(w, http.StatusOK, model.Welcome{Message: h.service.Welcome()})
+}
+
+// HandleHealth answers 503 while the tracker circuit is open, so load
+// balancers stop sending requests that would fail anyway.
+func (h *TaskHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	res := h.service.Health()
+	status := http.StatusOK
+	if res.Status == model.HealthUnavailable {
+		status = http.StatusServiceUnavailable
 	}
+	writeJSON(w, status, res)
 }
 
 func (h *TaskHandler) HandleTask(w http.ResponseWriter, r *http.Request) {
@@ -65,80 +160,388 @@ func (h *TaskHandler) HandleTask(w http.ResponseWriter, r *http.Request) {
 
 	masterTask, err := unmarshalMasterTask(w, r)
 	if err != nil {
-		res := map[string]string{
-			"error":   fmt.Sprintf("%+v", http.StatusBadRequest),
-			"message": err.Error(),
-		}
+		writeError(w, statusFor(err), err)
+		return
+	}
 
-		jsonRes, err := json.Marshal(res)
-		if err != nil {
-			log.Fatalf("error marshaling json response. %s", err)
-		}
+	res, err := h.service.FilterTask(r.Context(), masterTask)
+	if err != nil {
+		log.Printf("error creating task. %s", err)
+		writeError(w, statusFor(err), err)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
+	writeJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetSchema returns the JSON Schema of the create requests of a card type.
+func (h *TaskHandler) HandleGetSchema(w http.ResponseWriter, r *http.Request) {
+	cardType := strings.TrimPrefix(r.URL.Path, schemas+"/")
+	log.Printf("getting schema of %s cards", cardType)
+
+	schema, err := h.service.Schema(cardType)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	writeJSON(w, http.StatusOK, schema)
+}
+
+func (h *TaskHandler) routeCard(w http.ResponseWriter, r *http.Request) {
+	_, action := cardPath(r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodGet && action == "":
+		h.HandleGetCard(w, r)
+	case r.Method == http.MethodPatch && action == "":
+		h.HandleUpdateCard(w, r)
+	case r.Method == http.MethodDelete && action == "":
+		h.HandleDeleteCard(w, r)
+	case r.Method == http.MethodPost && action == "archive":
+		h.HandleArchiveCard(w, r)
+	case r.Method == http.MethodPost && action == "transition":
+		h.HandleTransitionCard(w, r)
+	case r.Method == http.MethodPost && action == "comments":
+		h.HandleAddComment(w, r)
+	case r.Method == http.MethodGet && action == "comments":
+		h.HandleListComments(w, r)
+	case r.Method == http.MethodPost && action == "attachments":
+		h.HandleAddAttachment(w, r)
+	case r.Method == http.MethodGet && action == "checklists":
+		h.HandleListChecklists(w, r)
+	case r.Method == http.MethodPatch && strings.HasPrefix(action, "checkitems/"):
+		h.HandleSetCheckItem(w, r)
+	default:
+		notFound(w, r)
+	}
+}
+
+func (h *TaskHandler) HandleListCards(w http.ResponseWriter, r *http.Request) {
+	log.Printf("listing cards")
 
-		_, err = w.Write(jsonRes)
+	params := r.URL.Query()
+	query := model.CardQuery{
+		Type:     params.Get("type"),
+		Category: params.Get("category"),
+		State:    params.Get("state"),
+		ListId:   params.Get("list_id"),
+		Text:     params.Get("q"),
+		Cursor:   params.Get("cursor"),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
 		if err != nil {
-			log.Fatalf("error writing json response. %s", err)
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", limit))
+			return
 		}
+		query.Limit = n
+	}
+
+	res, err := h.service.ListCards(r.Context(), query)
+	if err != nil {
+		writeError(w, statusFor(err), err)
 		return
 	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) HandleGetCard(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("getting card %s", id)
 
-	res, err := h.service.FilterTask(masterTask)
+	res, err := h.service.GetCard(r.Context(), id)
 	if err != nil {
-		log.Fatalf("error creating task. %s", err)
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) HandleUpdateCard(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("updating card %s", id)
+
+	var update model.CardUpdate
+	if err := unmarshalBody(r, &update); err != nil {
+		writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	jsonRes, err := json.Marshal(res)
+	res, err := h.service.UpdateCard(r.Context(), id, update)
 	if err != nil {
-		log.Fatalf("error marshaling json response. %s", err)
+		writeError(w, statusFor(err), err)
+		return
 	}
+	writeJSON(w, http.StatusOK, res)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+func (h *TaskHandler) HandleArchiveCard(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("archiving card %s", id)
 
-	_, err = w.Write(jsonRes)
+	res, err := h.service.ArchiveCard(r.Context(), id)
 	if err != nil {
-		log.Fatalf("error writing json response. %s", err)
+		writeError(w, statusFor(err), err)
+		return
 	}
+	writeJSON(w, http.StatusOK, res)
 }
 
-func notFound(w http.ResponseWriter, r *http.Request) {
-	log.Printf("%s endpoint not found", r.URL)
+func (h *TaskHandler) HandleTransitionCard(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("transitioning card %s", id)
+
+	var transition model.Transition
+	if err := unmarshalBody(r, &transition); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.service.TransitionCard(r.Context(), id, transition.State)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) HandleAddComment(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("adding a comment to card %s", id)
+
+	var comment model.Comment
+	if err := unmarshalBody(r, &comment); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.service.AddComment(r.Context(), id, comment)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
+func (h *TaskHandler) HandleListComments(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("getting the comments of card %s", id)
 
-	_, err := w.Write([]byte("not found"))
+	res, err := h.service.ListComments(r.Context(), id)
 	if err != nil {
-		log.Fatalf("error writing json response. %s", err)
+		writeError(w, statusFor(err), err)
+		return
 	}
+	writeJSON(w, http.StatusOK, model.CommentList{Comments: res})
 }
 
+func (h *TaskHandler) HandleAddAttachment(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("attaching a file to card %s", id)
+
+	// Leave room for the multipart boundaries and the other form fields
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("Error while reading the attachment. %s", err)
+		writeError(w, http.StatusBadRequest, fmt.Errorf("expected a multipart/form-data request with a 'file' field up to %d MB", maxAttachmentSize>>20))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAttachmentSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file larger than %d MB", maxAttachmentSize>>20))
+		return
+	}
+
+	name := header.Filename
+	if n := r.FormValue("name"); n != "" {
+		name = n
+	}
+	mimeType := header.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = mime.TypeByExtension(filepath.Ext(header.Filename))
+	}
+
+	res, err := h.service.AddAttachment(r.Context(), id, model.File{Name: name, MimeType: mimeType, Content: file})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *TaskHandler) HandleListChecklists(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("getting the checklists of card %s", id)
+
+	res, err := h.service.ListChecklists(r.Context(), id)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, model.ChecklistList{Checklists: res})
+}
+
+func (h *TaskHandler) HandleSetCheckItem(w http.ResponseWriter, r *http.Request) {
+	id, action := cardPath(r.URL.Path)
+	itemId := strings.TrimPrefix(action, "checkitems/")
+	log.Printf("updating check item %s of card %s", itemId, id)
+
+	var item model.CheckItem
+	if err := unmarshalBody(r, &item); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := h.service.SetCheckItem(r.Context(), id, itemId, item)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) HandleDeleteCard(w http.ResponseWriter, r *http.Request) {
+	id, _ := cardPath(r.URL.Path)
+	log.Printf("deleting card %s", id)
+
+	err := h.service.DeleteCard(r.Context(), id)
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	log.Printf("%s endpoint not found", r.URL)
+	writeError(w, http.StatusNotFound, fmt.Errorf("%s %s is not an endpoint of this API", r.Method, r.URL.Path))
+}
+
+// unmarshalMasterTask decodes the create request, rejecting unknown fields.
+// When it does not decode, every unknown or mistyped field is reported.
 func unmarshalMasterTask(w http.ResponseWriter, r *http.Request) (model.MasterTask, error) {
-	// Read body
-	b, err := ioutil.ReadAll(r.Body)
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Fatalf("Error closing body")
-			return
-		}
-	}(r.Body)
+	defer r.Body.Close()
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
 	if err != nil {
 		log.Println("Error while reading request body")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		return model.MasterTask{}, fieldError("body", fmt.Sprintf("must be at most %d bytes", maxBodySize))
 	}
 
-	// Unmarshal
 	var masterTask model.MasterTask
 
-	err = json.Unmarshal(b, &masterTask)
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&masterTask)
+	if err == nil && decoder.More() {
+		err = fmt.Errorf("trailing data")
+	}
 	if err != nil {
-		log.Println("Error while unmarshalling request")
-		return model.MasterTask{}, fmt.Errorf("error while unmarshalling request")
+		log.Printf("Error while unmarshalling request. %s", err)
+		return model.MasterTask{}, decodeErrors(b)
 	}
 	return masterTask, nil
 }
+
+// decodeErrors decodes the fields of the body one by one to report all the
+// unknown fields and those of the wrong type.
+func decodeErrors(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fieldError("body", "must be a single JSON object")
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []model.FieldError
+	for _, name := range names {
+		t, ok := taskFields[name]
+		if !ok {
+			errs = append(errs, model.FieldError{Field: name, Reason: "is not a known field"})
+			continue
+		}
+		if err := json.Unmarshal(raw[name], reflect.New(t).Interface()); err != nil {
+			errs = append(errs, model.FieldError{Field: name, Reason: "must be " + jsonType(t)})
+		}
+	}
+	if len(errs) == 0 {
+		return fieldError("body", "must be a single JSON object")
+	}
+	return &model.ValidationError{Fields: errs}
+}
+
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+	return fields
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array of strings"
+	case reflect.Map:
+		return "an object"
+	default:
+		return "a " + t.Kind().String()
+	}
+}
+
+func fieldError(field string, reason string) error {
+	return &model.ValidationError{Fields: []model.FieldError{{Field: field, Reason: reason}}}
+}
+
+// cardPath splits /api/v1/cards/{id}/{action} into the card id and the optional action.
+func cardPath(path string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(path, cards+"/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func unmarshalBody(r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		log.Println("Error while unmarshalling request")
+		return fmt.Errorf("error while unmarshalling request")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	jsonRes, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error marshaling json response. %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+
+	_, err = w.Write(jsonRes)
+	if err != nil {
+		log.Printf("error writing json response. %s", err)
+	}
+}
@@ -1,13 +1,23 @@
 package controller
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -20,9 +30,90 @@ func (m *MockTaskService) Welcome() string {
 	return args.String(0)
 }
 
-func (m *MockTaskService) FilterTask(_ model.MasterTask) (map[string]string, error) {
+func (m *MockTaskService) Health() model.Health {
 	args := m.Called()
-	return args.Get(0).(map[string]string), args.Error(1)
+	return args.Get(0).(model.Health)
+}
+
+func (m *MockTaskService) FilterTask(_ context.Context, _ model.MasterTask) (*model.CardCreated, error) {
+	args := m.Called()
+	res, _ := args.Get(0).(*model.CardCreated)
+	return res, args.Error(1)
+}
+
+func (m *MockTaskService) Schema(cardType string) (map[string]interface{}, error) {
+	args := m.Called(cardType)
+	schema, _ := args.Get(0).(map[string]interface{})
+	return schema, args.Error(1)
+}
+
+func (m *MockTaskService) GetCard(_ context.Context, id string) (*model.Card, error) {
+	args := m.Called(id)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTaskService) UpdateCard(_ context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	args := m.Called(id, update)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTaskService) ArchiveCard(_ context.Context, id string) (*model.Card, error) {
+	args := m.Called(id)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTaskService) DeleteCard(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) TransitionCard(_ context.Context, id string, state string) (*model.Card, error) {
+	args := m.Called(id, state)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTaskService) ListCards(_ context.Context, query model.CardQuery) (*model.CardList, error) {
+	args := m.Called(query)
+	list, _ := args.Get(0).(*model.CardList)
+	return list, args.Error(1)
+}
+
+func (m *MockTaskService) AddComment(_ context.Context, cardId string, comment model.Comment) (*model.Comment, error) {
+	args := m.Called(cardId, comment)
+	c, _ := args.Get(0).(*model.Comment)
+	return c, args.Error(1)
+}
+
+func (m *MockTaskService) ListComments(_ context.Context, cardId string) ([]model.Comment, error) {
+	args := m.Called(cardId)
+	comments, _ := args.Get(0).([]model.Comment)
+	return comments, args.Error(1)
+}
+
+func (m *MockTaskService) AddAttachment(_ context.Context, cardId string, file model.File) (*model.Attachment, error) {
+	content, _ := io.ReadAll(file.Content)
+	args := m.Called(cardId, file.Name, file.MimeType, string(content))
+	a, _ := args.Get(0).(*model.Attachment)
+	return a, args.Error(1)
+}
+
+func (m *MockTaskService) ListChecklists(_ context.Context, cardId string) ([]model.Checklist, error) {
+	args := m.Called(cardId)
+	checklists, _ := args.Get(0).([]model.Checklist)
+	return checklists, args.Error(1)
+}
+
+func (m *MockTaskService) SetCheckItem(_ context.Context, cardId string, itemId string, item model.CheckItem) (*model.CheckItem, error) {
+	args := m.Called(cardId, itemId, item)
+	i, _ := args.Get(0).(*model.CheckItem)
+	return i, args.Error(1)
+}
+
+func cardArg(args mock.Arguments, i int) *model.Card {
+	if c, ok := args.Get(i).(*model.Card); ok {
+		return c
+	}
+	return nil
 }
 
 func TestTaskHandler_TestHandleWelcome(t *testing.T) {
@@ -72,15 +163,15 @@ func TestTaskHandler_HandleTask(t *testing.T) {
 	recorder := httptest.NewRecorder()
 
 	// When the HandleTask method is invoked
-	serviceRes := map[string]string{
-		"board_id": "890uio",
-		"category": "Maintenance",
-		"id":       "123qwe",
-		"list_id":  "asd456",
-		"message":  "card created",
-		"title":    "Brush keys on all panels",
-		"type":     "task",
-		"url":      "https://example.com/c/ueMYnIVX/69-brush-keys-on-all-boards",
+	serviceRes := &model.CardCreated{
+		BoardId:  "890uio",
+		Category: "Maintenance",
+		Id:       "123qwe",
+		ListId:   "asd456",
+		Message:  "card created",
+		Title:    "Brush keys on all panels",
+		Type:     "task",
+		Url:      "https://example.com/c/ueMYnIVX/69-brush-keys-on-all-boards",
 	}
 
 	mockTaskService.On("FilterTask").Return(serviceRes, nil)
@@ -88,27 +179,635 @@ func TestTaskHandler_HandleTask(t *testing.T) {
 	handler.HandleTask(recorder, req)
 
 	// Then check that the expected object is valid
-	var res map[string]string
+	var res model.CardCreated
 	err = json.Unmarshal(recorder.Body.Bytes(), &res)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal JSON response: %s", err)
 	}
+	assert.Equal(t, *serviceRes, res)
+
+	expected := "card created"
+	if res.Message != expected {
+		t.Errorf("Expected message '%s', but got '%s'", expected, res.Message)
+	}
+	assert.NotContains(t, recorder.Body.String(), "checklist_id")
+}
+
+func TestTaskHandler_HandleTask_UnknownAssignee(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+	inputReq := `{"type": "issue", "title": "No pilot mode", "description": "Enable it", "assignees": ["robin"]}`
+
+	// Given an issue assigned to someone who is not a board member
+	req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(inputReq))
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	expectedRes := map[string]string{
-		"board_id": "890uio",
-		"category": "Maintenance",
-		"id":       "123qwe",
-		"list_id":  "asd456",
-		"message":  "card created",
-		"title":    "Brush keys on all panels",
-		"type":     "task",
-		"url":      "https://example.com/c/ueMYnIVX/69-brush-keys-on-all-boards",
+	recorder := httptest.NewRecorder()
+
+	// When the assignee cannot be resolved
+	mockTaskService.On("FilterTask").Return(nil, fmt.Errorf("unknown assignees robin: %w", model.ErrInvalidRequest))
+
+	handler.HandleTask(recorder, req)
+
+	// Then a bad request status is returned
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, recorder.Code)
+	}
+	assert.Contains(t, recorder.Body.String(), "unknown assignees robin")
+}
+
+func TestTaskHandler_HandleTask_InvalidFields(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+	inputReq := `{"type": "bug", "description": 42, "sevrity": "high", "steps": "Refuel", "labels": []}`
+
+	// Given a bug with misspelled and mistyped fields
+	req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(inputReq))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	handler.ServeHTTP(recorder, req)
+
+	// Then every offending field is listed and the service is not called
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	var res struct {
+		Errors []model.FieldError `json:"errors"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
 	}
+	assert.Equal(t, []model.FieldError{
+		{Field: "description", Reason: "must be a string"},
+		{Field: "labels", Reason: "is not a known field"},
+		{Field: "sevrity", Reason: "is not a known field"},
+		{Field: "steps", Reason: "must be an array of strings"},
+	}, res.Errors)
+	mockTaskService.AssertNotCalled(t, "FilterTask")
+}
+
+func TestTaskHandler_HandleGetSchema(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a get request for the schema of bugs and of an unknown type
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/schemas/bug", nil)
+	unknownReq := httptest.NewRequest(http.MethodGet, "/api/v1/schemas/spike", nil)
+	recorder := httptest.NewRecorder()
+	unknownRecorder := httptest.NewRecorder()
+
+	// When the requests are served
+	mockTaskService.On("Schema", "bug").Once().Return(map[string]interface{}{"title": "bug card"}, nil)
+	mockTaskService.On("Schema", "spike").Once().Return(nil, fmt.Errorf("card type spike: %w", model.ErrNotFound))
+	handler.ServeHTTP(recorder, req)
+	handler.ServeHTTP(unknownRecorder, unknownReq)
+
+	// Then the schema is returned, and unknown types are not found
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/schema+json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"title": "bug card"}`, recorder.Body.String())
+	assert.Equal(t, http.StatusNotFound, unknownRecorder.Code)
+}
 
-	if res["message"] != expectedRes["message"] {
-		t.Errorf("Expected message '%s', but got '%s'", expectedRes["message"], res["message"])
+func TestTaskHandler_HandleGetCard(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a get request for an existing card
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/cards/123qwe", nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if res["title"] != expectedRes["title"] {
-		t.Errorf("Expected title '%s', but got '%s'", expectedRes["title"], res["title"])
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	card := &model.Card{Id: "123qwe", Title: "Brush keys", BoardId: "890uio", ListId: "asd456"}
+	mockTaskService.On("GetCard", "123qwe").Once().Return(card, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the card is returned
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, recorder.Code)
+	}
+
+	var res model.Card
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, *card, res)
+}
+
+func TestTaskHandler_HandleGetCard_NotFound(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a get request for a missing card
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/cards/missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the tracker does not know the card
+	mockTaskService.On("GetCard", "missing").Once().Return(nil, fmt.Errorf("error: %w", model.ErrNotFound))
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then a not found status is returned
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestTaskHandler_HandleUpdateCard(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a patch request fixing the title of a card
+	req, err := http.NewRequest(http.MethodPatch, "/api/v1/cards/123qwe", strings.NewReader(`{"title": "Brush keys on all boards"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	update := model.CardUpdate{Title: "Brush keys on all boards"}
+	card := &model.Card{Id: "123qwe", Title: "Brush keys on all boards"}
+	mockTaskService.On("UpdateCard", "123qwe", update).Once().Return(card, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the updated card is returned
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, recorder.Code)
+	}
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestTaskHandler_HandleDeleteCard(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a delete request for a card
+	req, err := http.NewRequest(http.MethodDelete, "/api/v1/cards/123qwe", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	mockTaskService.On("DeleteCard", "123qwe").Once().Return(nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then no content is returned
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNoContent, recorder.Code)
+	}
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestTaskHandler_HandleTransitionCard(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request to move a card to done
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/cards/123qwe/transition", strings.NewReader(`{"state": "done"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	card := &model.Card{Id: "123qwe", ListId: "asd789"}
+	mockTaskService.On("TransitionCard", "123qwe", "done").Once().Return(card, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the moved card is returned
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, recorder.Code)
+	}
+
+	var res model.Card
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, "asd789", res.ListId)
+}
+
+func TestTaskHandler_HandleListCards(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request for the open bugs in the doing list
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/cards?type=bug&state=doing&q=fuel&limit=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	query := model.CardQuery{Type: "bug", State: "doing", Text: "fuel", Limit: 2}
+	list := &model.CardList{Cards: []model.Card{{Id: "1"}, {Id: "2"}}, NextCursor: "Mg"}
+	mockTaskService.On("ListCards", query).Once().Return(list, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the page of cards is returned
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, recorder.Code)
+	}
+
+	var res model.CardList
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, *list, res)
+}
+
+func TestTaskHandler_HandleListCards_InvalidLimit(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request with a non numeric limit
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/cards?limit=many", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	handler.ServeHTTP(recorder, req)
+
+	// Then it is rejected before reaching the service
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, recorder.Code)
+	}
+	mockTaskService.AssertNotCalled(t, "ListCards", mock.Anything)
+}
+
+func TestTaskHandler_HandleAddComment(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a status update posted on a bug
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/cards/123qwe/comments", strings.NewReader(`{"text": "Fix deployed to staging"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	comment := &model.Comment{Id: "c1", Text: "Fix deployed to staging", Author: "ci-bot"}
+	mockTaskService.On("AddComment", "123qwe", model.Comment{Text: "Fix deployed to staging"}).Once().Return(comment, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the comment is created
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, but got %d", http.StatusCreated, recorder.Code)
+	}
+
+	var res model.Comment
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, "c1", res.Id)
+}
+
+func TestTaskHandler_HandleListComments_Unsupported(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request for the comments of a card
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/cards/123qwe/comments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the backend has no comments support
+	mockTaskService.On("ListComments", "123qwe").Once().Return(nil, fmt.Errorf("comments: %w", model.ErrUnsupported))
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then not implemented is returned
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status code %d, but got %d", http.StatusNotImplemented, recorder.Code)
+	}
+}
+
+func TestTaskHandler_HandleAddAttachment(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a screenshot uploaded as multipart/form-data
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "dashboard.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("PNG"))
+	_ = writer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/cards/123qwe/attachments", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	attachment := &model.Attachment{Id: "a1", Name: "dashboard.png", Bytes: 3, MimeType: "image/png"}
+	mockTaskService.On("AddAttachment", "123qwe", "dashboard.png", "image/png", "PNG").Once().Return(attachment, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the attachment is created
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, but got %d", http.StatusCreated, recorder.Code)
+	}
+	mockTaskService.AssertExpectations(t)
+}
+
+func TestTaskHandler_HandleAddAttachment_NotMultipart(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a JSON body instead of a multipart form
+	req, err := http.NewRequest(http.MethodPost, "/api/v1/cards/123qwe/attachments", strings.NewReader(`{"file": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	handler.ServeHTTP(recorder, req)
+
+	// Then it is rejected
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, but got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestTaskHandler_HandleSetCheckItem(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request ticking off a check item
+	req, err := http.NewRequest(http.MethodPatch, "/api/v1/cards/123qwe/checkitems/ci1", strings.NewReader(`{"state": "complete"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	item := &model.CheckItem{Id: "ci1", Name: "Drain old oil", State: "complete"}
+	mockTaskService.On("SetCheckItem", "123qwe", "ci1", model.CheckItem{State: "complete"}).Once().Return(item, nil)
+
+	handler.ServeHTTP(recorder, req)
+
+	// Then the updated item is returned
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, but got %d", http.StatusOK, recorder.Code)
+	}
+
+	var res model.CheckItem
+	err = json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, *item, res)
+}
+
+func TestTaskHandler_ProblemResponse(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	// Given a request with a correlation id while the tracker times out
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/123qwe", nil)
+	req.Header.Set("X-Correlation-ID", "req-42")
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	mockTaskService.On("GetCard", "123qwe").Once().Return(nil, model.UpstreamError(http.StatusGatewayTimeout))
+	handler.ServeHTTP(recorder, req)
+
+	// Then a problem with the same correlation id is returned
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "req-42", recorder.Header().Get("X-Correlation-ID"))
+	assert.JSONEq(t, `{
+		"type": "/problems/tracker-timeout",
+		"title": "Gateway Timeout",
+		"status": 504,
+		"detail": "the tracker did not answer in time",
+		"correlation_id": "req-42"
+	}`, recorder.Body.String())
+}
+
+func TestTaskHandler_ProblemResponse_Credentials(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	for _, err := range []error{
+		// Given a tracker request that could not be sent
+		model.TransportError(&url.Error{
+			Op:  "Get",
+			URL: "http://127.0.0.1:1/1/cards/abc?key=SECRETKEY&token=SECRETTOKEN",
+			Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+		}),
+		model.TransportError(&url.Error{
+			Op:  "Get",
+			URL: "http://127.0.0.1:1/1/cards/abc?key=SECRETKEY&token=SECRETTOKEN",
+			Err: context.DeadlineExceeded,
+		}),
+		// Or any other error carrying the URL
+		fmt.Errorf("error reading https://api.trello.com/1/cards/abc?key=SECRETKEY&token=SECRETTOKEN: %w", model.ErrUpstream),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/abc", nil)
+		recorder := httptest.NewRecorder()
+
+		// When the request is served
+		mockTaskService.On("GetCard", "abc").Once().Return(nil, err)
+		handler.ServeHTTP(recorder, req)
+
+		// Then the problem does not carry the credentials
+		assert.GreaterOrEqual(t, recorder.Code, http.StatusInternalServerError)
+		assert.NotContains(t, recorder.Body.String(), "SECRETKEY")
+		assert.NotContains(t, recorder.Body.String(), "SECRETTOKEN")
+	}
+	assert.NotContains(t, model.TransportError(&url.Error{Op: "Get", URL: "http://x/?token=SECRETTOKEN", Err: io.EOF}).Error(), "SECRETTOKEN")
+}
+
+func TestTaskHandler_NotFound(t *testing.T) {
+	handler := New(new(MockTaskService))
+
+	// Given a request to an unknown endpoint
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/cards", nil)
+	recorder := httptest.NewRecorder()
+
+	// When the request is served
+	handler.ServeHTTP(recorder, req)
+
+	// Then a not found problem with a new correlation id is returned
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Len(t, recorder.Header().Get("X-Correlation-ID"), 32)
+	var res map[string]interface{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal JSON response: %s", err)
+	}
+	assert.Equal(t, recorder.Header().Get("X-Correlation-ID"), res["correlation_id"])
+}
+
+func TestTaskHandler_Timeout(t *testing.T) {
+	handler := New(new(MockTaskService))
+	handler.Timeout, handler.MaxTimeout = 5*time.Second, 30*time.Second
+
+	for header, timeout := range map[string]time.Duration{
+		"":      5 * time.Second,
+		"15s":   15 * time.Second,
+		"2":     2 * time.Second,
+		"1.5":   1500 * time.Millisecond,
+		"10m":   30 * time.Second,
+		"86400": 30 * time.Second,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/123qwe", nil)
+		req.Header.Set("X-Request-Timeout", header)
+
+		got, err := handler.timeout(req)
+
+		assert.NoError(t, err, header)
+		assert.Equal(t, timeout, got, header)
+	}
+}
+
+// ctxTaskService keeps the context of the GetCard calls.
+type ctxTaskService struct {
+	*MockTaskService
+	ctx context.Context
+}
+
+func (s *ctxTaskService) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	s.ctx = ctx
+	return s.MockTaskService.GetCard(ctx, id)
+}
+
+func TestTaskHandler_ShortTimeout(t *testing.T) {
+	svc := &ctxTaskService{MockTaskService: new(MockTaskService)}
+	handler := New(svc)
+	handler.Timeout = 5 * time.Second
+
+	for header, short := range map[string]bool{"": false, "2s": true, "5s": false, "30s": false} {
+		// Given a request asking for a timeout
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/123qwe", nil)
+		req.Header.Set("X-Request-Timeout", header)
+
+		// When the request is served
+		svc.On("GetCard", "123qwe").Once().Return(&model.Card{Id: "123qwe"}, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		// Then the tracker calls know whether it is shorter than the default
+		assert.Equal(t, short, model.ShortTimeout(svc.ctx), header)
+	}
+}
+
+func TestTaskHandler_InvalidTimeout(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	handler := New(mockTaskService)
+
+	for _, header := range []string{"soon", "0", "-5s", "1ns", "0.5"} {
+		// Given a request with an invalid or too short timeout header
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/cards/123qwe", nil)
+		req.Header.Set("X-Request-Timeout", header)
+		recorder := httptest.NewRecorder()
+
+		// When the request is served
+		handler.ServeHTTP(recorder, req)
+
+		// Then it is rejected without calling the tracker
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, header)
+		assert.Contains(t, recorder.Body.String(), "X-Request-Timeout", header)
+	}
+	mockTaskService.AssertNotCalled(t, "GetCard", "123qwe")
+}
+
+func TestTaskHandler_HandleHealth(t *testing.T) {
+	retryAt := time.Date(2023, 4, 1, 12, 0, 30, 0, time.UTC)
+	for status, health := range map[int]model.Health{
+		http.StatusOK: {
+			Status:  model.HealthOk,
+			Tracker: model.TrackerHealth{Name: "trello", Circuit: model.CircuitClosed},
+		},
+		http.StatusServiceUnavailable: {
+			Status:  model.HealthUnavailable,
+			Tracker: model.TrackerHealth{Name: "trello", Circuit: model.CircuitOpen, Failures: 5, RetryAt: &retryAt},
+		},
+	} {
+		mockTaskService := new(MockTaskService)
+		handler := New(mockTaskService)
+
+		// Given the health of the tracker
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+		recorder := httptest.NewRecorder()
+		mockTaskService.On("Health").Once().Return(health)
+
+		// When the health endpoint is called
+		handler.ServeHTTP(recorder, req)
+
+		// Then it answers with the state of the circuit
+		assert.Equal(t, status, recorder.Code)
+		var res model.Health
+		err := json.Unmarshal(recorder.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal JSON response: %s", err)
+		}
+		assert.Equal(t, health.Status, res.Status)
+		assert.Equal(t, health.Tracker.Circuit, res.Tracker.Circuit)
+		assert.Equal(t, health.Tracker.Failures, res.Tracker.Failures)
+	}
+}
+
+func TestStatusFor(t *testing.T) {
+	for err, status := range map[error]int{
+		model.UpstreamError(http.StatusNotFound):               http.StatusNotFound,
+		model.UpstreamError(http.StatusTooManyRequests):        http.StatusServiceUnavailable,
+		model.UpstreamError(http.StatusServiceUnavailable):     http.StatusServiceUnavailable,
+		model.UpstreamError(http.StatusGatewayTimeout):         http.StatusGatewayTimeout,
+		model.UpstreamError(http.StatusInternalServerError):    http.StatusBadGateway,
+		model.TransportError(context.DeadlineExceeded):         http.StatusGatewayTimeout,
+		model.TransportError(context.Canceled):                 http.StatusGatewayTimeout,
+		model.TransportError(errors.New("connection refused")): http.StatusServiceUnavailable,
+		errors.New("disk full"):                                http.StatusInternalServerError,
+	} {
+		assert.Equal(t, status, statusFor(err), err.Error())
 	}
 }
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const (
+	correlationHeader  = "X-Correlation-ID"
+	problemContentType = "application/problem+json"
+)
+
+// correlationIds are the ids accepted from clients, others are replaced.
+var correlationIds = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// problemTypes are the problem type URIs of each status, relative to the API.
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "/problems/invalid-request",
+	http.StatusNotFound:              "/problems/not-found",
+	http.StatusRequestEntityTooLarge: "/problems/too-large",
+	http.StatusInternalServerError:   "/problems/internal-error",
+	http.StatusNotImplemented:        "/problems/unsupported",
+	http.StatusBadGateway:            "/problems/tracker-error",
+	http.StatusServiceUnavailable:    "/problems/tracker-unavailable",
+	http.StatusGatewayTimeout:        "/problems/tracker-timeout",
+}
+
+// problemDetails replace the error of the statuses caused by the tracker or
+// the service itself, as it can carry tracker URLs and credentials or
+// internal details. The error is logged with the correlation id instead.
+var problemDetails = map[int]string{
+	http.StatusInternalServerError: "the service failed to handle the request",
+	http.StatusBadGateway:          "the tracker answered with an error",
+	http.StatusServiceUnavailable:  "the tracker cannot be reached or is rate limiting, try again later",
+	http.StatusGatewayTimeout:      "the tracker did not answer in time",
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, model.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, model.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, model.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, model.ErrUpstream):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes the error as a problem with the correlation id of the
+// request, so it can be found in the logs.
+func writeError(w http.ResponseWriter, status int, err error) {
+	id := correlationId(w)
+	log.Printf("[%s] error %d: %s", id, status, err)
+
+	res := model.Problem{
+		Type:          problemTypes[status],
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        err.Error(),
+		CorrelationId: id,
+	}
+	if res.Type == "" {
+		res.Type = "about:blank"
+	}
+	if detail, ok := problemDetails[status]; ok {
+		res.Detail = detail
+	}
+	var validation *model.ValidationError
+	if errors.As(err, &validation) {
+		res.Errors = validation.Fields
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("error marshaling problem response. %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(b); err != nil {
+		log.Printf("error writing problem response. %s", err)
+	}
+}
+
+// setCorrelationId echoes the correlation id sent by the client, or sets a
+// new one, in the response headers.
+func setCorrelationId(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(correlationHeader)
+	if !correlationIds.MatchString(id) {
+		id = newCorrelationId()
+	}
+	w.Header().Set(correlationHeader, id)
+}
+
+// correlationId returns the correlation id of the response, setting one for
+// handlers called without ServeHTTP.
+func correlationId(w http.ResponseWriter) string {
+	id := w.Header().Get(correlationHeader)
+	if id == "" {
+		id = newCorrelationId()
+		w.Header().Set(correlationHeader, id)
+	}
+	return id
+}
+
+func newCorrelationId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// recoverPanic answers 500 instead of dropping the connection when a
+// handler panics.
+func recoverPanic(w http.ResponseWriter, r *http.Request) {
+	if v := recover(); v != nil {
+		log.Printf("panic serving %s %s: %v", r.Method, r.URL.Path, v)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("internal error"))
+	}
+}
@@ -0,0 +1,280 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const (
+	apiVersion    = "2022-11-28"
+	stateClosed   = "closed"
+	notPlanned    = "not_planned"
+	defaultAPIURL = "https://api.github.com"
+)
+
+// Client is the GitHub Issues implementation of the service tracker backend.
+type Client struct {
+	URL        string
+	Token      string
+	Repository string
+	// Categories hold the labels and the optional milestone numbers tasks of
+	// each category are added to.
+	Categories []cfg.Category
+	client     *http.Client
+}
+
+type issueRequest struct {
+	Title       string   `json:"title,omitempty"`
+	Body        string   `json:"body,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Milestone   int      `json:"milestone,omitempty"`
+	Assignees   []string `json:"assignees,omitempty"`
+	State       string   `json:"state,omitempty"`
+	StateReason string   `json:"state_reason,omitempty"`
+}
+
+type label struct {
+	Name string `json:"name"`
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+type issue struct {
+	Number    int     `json:"number"`
+	Title     string  `json:"title"`
+	Body      string  `json:"body"`
+	State     string  `json:"state"`
+	HTMLURL   string  `json:"html_url"`
+	Labels    []label `json:"labels"`
+	Assignees []user  `json:"assignees"`
+}
+
+func New(cfg cfg.Config) *Client {
+	url := cfg.GitHubURL
+	if url == "" {
+		url = defaultAPIURL
+	}
+	c := Client{
+		URL:        url,
+		Token:      cfg.GitHubToken,
+		Repository: cfg.GitHubRepository,
+		Categories: cfg.CategoriesFor("github"),
+		client:     &http.Client{},
+	}
+	return &c
+}
+
+// CreateCard opens an issue labelled with the labels of the card type and
+// those of its category, severity and priority. Tasks of a category with a
+// milestone are added to it.
+func (c *Client) CreateCard(ctx context.Context, request model.NewCard) (*model.Card, error) {
+	log.Printf("creating a %s with GitHub API in repository %s and title: %s", request.Type, c.Repository, request.Title)
+
+	label, milestone := c.setCategory(request.Category)
+
+	payload := issueRequest{
+		Title:     request.Title,
+		Body:      request.Description,
+		Labels:    append([]string{}, request.Labels...),
+		Milestone: milestone,
+	}
+	if label != "" {
+		payload.Labels = append(payload.Labels, label)
+	}
+	if request.Severity != "" {
+		payload.Labels = append(payload.Labels, "severity: "+request.Severity)
+	}
+	if request.Priority != "" {
+		payload.Labels = append(payload.Labels, "priority: "+request.Priority)
+	}
+	return c.create(ctx, payload, request.Assignees)
+}
+
+func (c *Client) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/repos/%s/issues/%s", c.URL, c.Repository, id)
+	log.Printf("getting issue %s from GitHub API", id)
+
+	resp := issue{}
+
+	err := c.call(ctx, nil, &resp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting issue %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return c.toCard(resp), nil
+}
+
+// UpdateCard edits the issue. The list id is the issue state, either "open" or "closed".
+func (c *Client) UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	payload := issueRequest{
+		Title:  update.Title,
+		Body:   update.Description,
+		Labels: update.Labels,
+		State:  update.ListId,
+	}
+	return c.update(ctx, id, payload)
+}
+
+func (c *Client) MoveCard(ctx context.Context, id string, listId string) (*model.Card, error) {
+	return c.update(ctx, id, issueRequest{State: listId})
+}
+
+// DeleteCard closes the issue as not planned, the REST API has no way to delete issues.
+func (c *Client) DeleteCard(ctx context.Context, id string) error {
+	_, err := c.update(ctx, id, issueRequest{State: stateClosed, StateReason: notPlanned})
+	return err
+}
+
+func (c *Client) create(ctx context.Context, payload issueRequest, assignees []string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/repos/%s/issues", c.URL, c.Repository)
+
+	logins, err := c.resolveAssignees(ctx, assignees)
+	if err != nil {
+		return nil, err
+	}
+	payload.Assignees = logins
+	resp := issue{}
+
+	err = c.call(ctx, payload, &resp, http.MethodPost, url)
+	if err != nil {
+		log.Printf("error while creating a GitHub issue")
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return c.toCard(resp), nil
+}
+
+// loginNames are the characters GitHub allows in user names, so an assignee
+// cannot change the path of the request checking it.
+var loginNames = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// resolveAssignees checks that every assignee can be assigned to issues of
+// the repository, since GitHub silently drops the ones that cannot.
+func (c *Client) resolveAssignees(ctx context.Context, assignees []string) ([]string, error) {
+	var logins, unknown []string
+	for _, assignee := range assignees {
+		login := strings.TrimPrefix(strings.TrimSpace(assignee), "@")
+		if !loginNames.MatchString(login) {
+			unknown = append(unknown, assignee)
+			continue
+		}
+		url := fmt.Sprintf("%s/repos/%s/assignees/%s", c.URL, c.Repository, login)
+
+		err := c.call(ctx, nil, nil, http.MethodGet, url)
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			unknown = append(unknown, assignee)
+		case err != nil:
+			log.Printf("error while checking assignee %s", assignee)
+			return nil, fmt.Errorf("error: %w", err)
+		default:
+			logins = append(logins, login)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown assignees %s, they must be GitHub users with access to %s: %w",
+			strings.Join(unknown, ", "), c.Repository, model.ErrInvalidRequest)
+	}
+	return logins, nil
+}
+
+func (c *Client) update(ctx context.Context, id string, payload issueRequest) (*model.Card, error) {
+	url := fmt.Sprintf("%s/repos/%s/issues/%s", c.URL, c.Repository, id)
+	log.Printf("updating issue %s with GitHub API", id)
+
+	resp := issue{}
+
+	err := c.call(ctx, payload, &resp, http.MethodPatch, url)
+	if err != nil {
+		log.Printf("error while updating issue %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return c.toCard(resp), nil
+}
+
+func (c *Client) call(ctx context.Context, request interface{}, response interface{}, httpMethod string, url string) error {
+
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("error marshaling request")
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request, %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/vnd.github+json")
+	req.Header.Add("X-GitHub-Api-Version", apiVersion)
+	req.Header.Add("Authorization", "Bearer "+c.Token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return model.TransportError(err)
+	}
+	defer resp.Body.Close()
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body, %w", err)
+	}
+
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		log.Printf("response error, code: %v", resp.StatusCode)
+		return model.UpstreamError(resp.StatusCode)
+	default:
+		log.Printf("successful client response. code: %v", resp.StatusCode)
+		if response != nil && len(output) > 0 {
+			_ = json.Unmarshal(output, response)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) toCard(i issue) *model.Card {
+	card := model.Card{
+		Id:          strconv.Itoa(i.Number),
+		Title:       i.Title,
+		Description: i.Body,
+		Url:         i.HTMLURL,
+		BoardId:     c.Repository,
+		ListId:      i.State,
+		Closed:      i.State == stateClosed,
+	}
+	for _, l := range i.Labels {
+		card.Labels = append(card.Labels, l.Name)
+	}
+	for _, u := range i.Assignees {
+		card.Members = append(card.Members, u.Login)
+	}
+	return &card
+}
+
+// setCategory returns the label and milestone of the category, matched by
+// name or alias.
+func (c *Client) setCategory(category string) (string, int) {
+	v, _ := cfg.FindCategory(c.Categories, category)
+	if v.Label == "" {
+		return v.LabelName, v.Milestone
+	}
+	return v.Label, v.Milestone
+}
@@ -0,0 +1,168 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(url string) *Client {
+	return New(cfg.Config{
+		GitHub: cfg.GitHub{
+			GitHubURL:               url,
+			GitHubToken:             "ghp_ABC123",
+			GitHubRepository:        "spacex/dashboard",
+			GitHubMaintenanceLabel:  "maintenance",
+			GitHubResearchLabel:     "research",
+			GitHubTestLabel:         "test",
+			GitHubResearchMilestone: 4,
+		},
+	})
+}
+
+func TestClient_CreateCard_Task(t *testing.T) {
+
+	// GitHub API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/spacex/dashboard/issues" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer ghp_ABC123" {
+			t.Errorf("expected bearer token, got %s", r.Header.Get("Authorization"))
+		}
+
+		var req issueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, "Measure drag", req.Title)
+		assert.Equal(t, []string{"research"}, req.Labels)
+		assert.Equal(t, 4, req.Milestone)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{
+			"number": 1347,
+			"title": "Measure drag",
+			"state": "open",
+			"html_url": "https://github.com/spacex/dashboard/issues/1347",
+			"labels": [{"name": "research"}]}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	card, err := c.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Measure drag", Category: "Research"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "1347", card.Id)
+	assert.Equal(t, "https://github.com/spacex/dashboard/issues/1347", card.Url)
+	assert.Equal(t, "spacex/dashboard", card.BoardId)
+	assert.Equal(t, "open", card.ListId)
+	assert.Equal(t, []string{"research"}, card.Labels)
+}
+
+func TestClient_CreateCard_Bug(t *testing.T) {
+
+	// GitHub API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req issueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, []string{"bug", "severity: high", "priority: low"}, req.Labels)
+		assert.Zero(t, req.Milestone)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 1348, "state": "open", "html_url": "https://github.com/spacex/dashboard/issues/1348"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	card, err := c.CreateCard(context.Background(), model.NewCard{
+		Type:        "bug",
+		Title:       "bug-high-12",
+		Description: "Fuel indicator not working",
+		Labels:      []string{"bug"},
+		Severity:    model.SeverityHigh,
+		Priority:    model.PriorityLow,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "1348", card.Id)
+}
+
+func TestClient_DeleteCard(t *testing.T) {
+
+	// GitHub API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/repos/spacex/dashboard/issues/1348" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var req issueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, "closed", req.State)
+		assert.Equal(t, "not_planned", req.StateReason)
+
+		_, _ = w.Write([]byte(`{"number": 1348, "state": "closed"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	err := c.DeleteCard(context.Background(), "1348")
+
+	assert.NoError(t, err)
+}
+
+func TestClient_GetCard_Error(t *testing.T) {
+
+	// GitHub API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	_, err := c.GetCard(context.Background(), "9999")
+
+	assert.Error(t, err)
+}
+
+func TestClient_CreateCard_InvalidAssignee(t *testing.T) {
+	requests := 0
+
+	// GitHub API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	_, err := c.CreateCard(context.Background(), model.NewCard{
+		Type:      "task",
+		Title:     "Measure drag",
+		Assignees: []string{"nobody/../../../../user?x="},
+	})
+
+	// The assignee is rejected without building a request from it
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	assert.Zero(t, requests)
+}
@@ -0,0 +1,349 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const (
+	issuePath      = "rest/api/2/issue"
+	userSearchPath = "rest/api/2/user/search"
+	dateLayout     = "2006-01-02"
+)
+
+// Jira issue types the built-in card types are mapped onto. Other card types
+// are created as tasks unless JIRA_ISSUE_TYPES says otherwise.
+const (
+	storyType = "Story"
+	bugType   = "Bug"
+	taskType  = "Task"
+)
+
+// Client is the Jira Cloud implementation of the service tracker backend.
+type Client struct {
+	URL        string
+	Email      string
+	Token      string
+	ProjectKey string
+	Component  string
+	IssueTypes map[string]string
+	Categories []cfg.Category
+	client     *http.Client
+}
+
+type issueFields struct {
+	Project     *project    `json:"project,omitempty"`
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	IssueType   *issueType  `json:"issuetype,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	Components  []component `json:"components,omitempty"`
+	Status      *status     `json:"status,omitempty"`
+	DueDate     string      `json:"duedate,omitempty"`
+	Assignee    *user       `json:"assignee,omitempty"`
+	Priority    *priority   `json:"priority,omitempty"`
+}
+
+type priority struct {
+	Name string `json:"name"`
+}
+
+// priorities maps the bug priorities onto the default Jira priority scheme.
+var priorities = map[string]string{
+	model.PriorityHigh:   "High",
+	model.PriorityMedium: "Medium",
+	model.PriorityLow:    "Low",
+}
+
+type user struct {
+	AccountId    string `json:"accountId"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	DisplayName  string `json:"displayName,omitempty"`
+}
+
+type project struct {
+	Key string `json:"key"`
+}
+
+type issueType struct {
+	Name string `json:"name"`
+}
+
+type component struct {
+	Name string `json:"name"`
+}
+
+type status struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type issue struct {
+	Id     string      `json:"id,omitempty"`
+	Key    string      `json:"key,omitempty"`
+	Fields issueFields `json:"fields"`
+}
+
+type transition struct {
+	Transition struct {
+		Id string `json:"id"`
+	} `json:"transition"`
+}
+
+func New(cfg cfg.Config) *Client {
+	c := Client{
+		URL:        cfg.JiraURL,
+		Email:      cfg.JiraEmail,
+		Token:      cfg.JiraToken,
+		ProjectKey: cfg.JiraProjectKey,
+		Component:  cfg.JiraComponent,
+		IssueTypes: map[string]string{"issue": storyType, "bug": bugType, "task": taskType},
+		Categories: cfg.CategoriesFor("jira"),
+		client:     &http.Client{},
+	}
+	for cardType, issueType := range cfg.JiraIssueTypes {
+		c.IssueTypes[cardType] = issueType
+	}
+	return &c
+}
+
+// CreateCard opens an issue of the Jira issue type mapped to the card type.
+// The severity becomes a label, e.g. "severity-critical", and the priority
+// the Jira priority.
+func (c *Client) CreateCard(ctx context.Context, request model.NewCard) (*model.Card, error) {
+	log.Printf("creating a %s with Jira API in project %s and title: %s", request.Type, c.ProjectKey, request.Title)
+	fields := issueFields{
+		Summary:     request.Title,
+		Description: request.Description,
+		IssueType:   &issueType{Name: c.issueType(request.Type)},
+		Labels:      append([]string{}, request.Labels...),
+	}
+	if label := c.setLabel(request.Category); label != "" {
+		fields.Labels = append(fields.Labels, label)
+	}
+	if request.Severity != "" {
+		fields.Labels = append(fields.Labels, "severity-"+request.Severity)
+	}
+	if name, ok := priorities[request.Priority]; ok {
+		fields.Priority = &priority{Name: name}
+	}
+	return c.create(ctx, fields, request.Assignees, request.Schedule)
+}
+
+func (c *Client) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s?fields=summary,description,labels,status,project,duedate,assignee", c.URL, issuePath, id)
+	log.Printf("getting issue %s from Jira API", id)
+
+	resp := issue{}
+
+	err := c.call(ctx, nil, &resp, http.MethodGet, url)
+	if err != nil {
+		log.Printf("error while getting issue %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return c.toCard(resp), nil
+}
+
+func (c *Client) UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s", c.URL, issuePath, id)
+	log.Printf("updating issue %s with Jira API", id)
+
+	payload := issue{Fields: issueFields{
+		Summary:     update.Title,
+		Description: update.Description,
+		Labels:      update.Labels,
+	}}
+
+	err := c.call(ctx, payload, nil, http.MethodPut, url)
+	if err != nil {
+		log.Printf("error while updating issue %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+
+	if update.ListId != "" {
+		return c.MoveCard(ctx, id, update.ListId)
+	}
+	return c.GetCard(ctx, id)
+}
+
+// MoveCard applies the workflow transition with the given id to the issue.
+// Jira has no lists, so the transition id plays that role.
+func (c *Client) MoveCard(ctx context.Context, id string, listId string) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s/%s/transitions", c.URL, issuePath, id)
+	log.Printf("transitioning issue %s with Jira API", id)
+
+	payload := transition{}
+	payload.Transition.Id = listId
+
+	err := c.call(ctx, payload, nil, http.MethodPost, url)
+	if err != nil {
+		log.Printf("error while transitioning issue %s", id)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	return c.GetCard(ctx, id)
+}
+
+func (c *Client) DeleteCard(ctx context.Context, id string) error {
+	url := fmt.Sprintf("%s/%s/%s", c.URL, issuePath, id)
+	log.Printf("deleting issue %s with Jira API", id)
+
+	err := c.call(ctx, nil, nil, http.MethodDelete, url)
+	if err != nil {
+		log.Printf("error while deleting issue %s", id)
+		return fmt.Errorf("error: %w", err)
+	}
+	return nil
+}
+
+// create opens the issue. Jira only keeps the due day, so the start date and
+// the reminder of the schedule are not sent.
+func (c *Client) create(ctx context.Context, fields issueFields, assignees []string, schedule model.Schedule) (*model.Card, error) {
+	url := fmt.Sprintf("%s/%s", c.URL, issuePath)
+
+	assignee, err := c.resolveAssignee(ctx, assignees)
+	if err != nil {
+		return nil, err
+	}
+
+	fields.Project = &project{Key: c.ProjectKey}
+	fields.Assignee = assignee
+	payload := issue{Fields: fields}
+	if c.Component != "" {
+		payload.Fields.Components = []component{{Name: c.Component}}
+	}
+	if schedule.Due != nil {
+		payload.Fields.DueDate = schedule.Due.Format(dateLayout)
+	}
+	resp := issue{}
+
+	err = c.call(ctx, payload, &resp, http.MethodPost, url)
+	if err != nil {
+		log.Printf("error while creating a %s", fields.IssueType.Name)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+
+	resp.Fields.Project = &project{Key: c.ProjectKey}
+	return c.toCard(resp), nil
+}
+
+// resolveAssignee finds the account of the assignee by email or name. Jira
+// issues have a single assignee.
+func (c *Client) resolveAssignee(ctx context.Context, assignees []string) (*user, error) {
+	switch len(assignees) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("jira issues accept a single assignee: %w", model.ErrInvalidRequest)
+	}
+
+	query := strings.TrimPrefix(strings.TrimSpace(assignees[0]), "@")
+	endpoint := fmt.Sprintf("%s/%s?query=%s", c.URL, userSearchPath, url.QueryEscape(query))
+	log.Printf("searching user %s with Jira API", query)
+
+	var users []user
+
+	err := c.call(ctx, nil, &users, http.MethodGet, endpoint)
+	if err != nil {
+		log.Printf("error while searching user %s", query)
+		return nil, fmt.Errorf("error: %w", err)
+	}
+	if len(users) != 1 {
+		return nil, fmt.Errorf("unknown assignees %s, found %d Jira users: %w", assignees[0], len(users), model.ErrInvalidRequest)
+	}
+	return &user{AccountId: users[0].AccountId}, nil
+}
+
+func (c *Client) call(ctx context.Context, request interface{}, response interface{}, httpMethod string, url string) error {
+
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("error marshaling request")
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, httpMethod, url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request, %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	req.SetBasicAuth(c.Email, c.Token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return model.TransportError(err)
+	}
+	defer resp.Body.Close()
+
+	output, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body, %w", err)
+	}
+
+	switch {
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		log.Printf("response error, code: %v", resp.StatusCode)
+		return model.UpstreamError(resp.StatusCode)
+	default:
+		log.Printf("successful client response. code: %v", resp.StatusCode)
+		if response != nil && len(output) > 0 {
+			_ = json.Unmarshal(output, response)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) toCard(i issue) *model.Card {
+	card := model.Card{
+		Id:          i.Key,
+		Title:       i.Fields.Summary,
+		Description: i.Fields.Description,
+		Url:         fmt.Sprintf("%s/browse/%s", c.URL, i.Key),
+		Labels:      i.Fields.Labels,
+	}
+	if i.Fields.Project != nil {
+		card.BoardId = i.Fields.Project.Key
+	}
+	if i.Fields.Status != nil {
+		card.ListId = i.Fields.Status.Id
+	}
+	if due, err := time.Parse(dateLayout, i.Fields.DueDate); err == nil {
+		card.Due = &due
+	}
+	if i.Fields.Assignee != nil {
+		card.Members = []string{i.Fields.Assignee.AccountId}
+	}
+	return &card
+}
+
+func (c *Client) issueType(cardType string) string {
+	if issueType, ok := c.IssueTypes[cardType]; ok {
+		return issueType
+	}
+	return taskType
+}
+
+// setLabel returns the label of the category, matched by name or alias.
+func (c *Client) setLabel(category string) string {
+	v, _ := cfg.FindCategory(c.Categories, category)
+	if v.Label == "" {
+		return v.LabelName
+	}
+	return v.Label
+}
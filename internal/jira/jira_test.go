@@ -0,0 +1,175 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(url string) *Client {
+	return New(cfg.Config{
+		Jira: cfg.Jira{
+			JiraURL:              url,
+			JiraEmail:            "ops@example.com",
+			JiraToken:            "ABC123",
+			JiraProjectKey:       "SPX",
+			JiraComponent:        "Dashboard",
+			JiraMaintenanceLabel: "maintenance",
+			JiraResearchLabel:    "research",
+			JiraTestLabel:        "test",
+			JiraIssueTypes:       map[string]string{"incident": "Incident"},
+		},
+	})
+}
+
+func TestClient_CreateCard_Bug(t *testing.T) {
+
+	// Jira API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/rest/api/2/issue" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "ops@example.com" || pass != "ABC123" {
+			t.Errorf("expected basic auth credentials, got %s:%s", user, pass)
+		}
+
+		var req issue
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, "SPX", req.Fields.Project.Key)
+		assert.Equal(t, "Bug", req.Fields.IssueType.Name)
+		assert.Equal(t, "bug-critical-12", req.Fields.Summary)
+		assert.Equal(t, []string{"bug", "severity-critical"}, req.Fields.Labels)
+		assert.Equal(t, &priority{Name: "High"}, req.Fields.Priority)
+		assert.Equal(t, []component{{Name: "Dashboard"}}, req.Fields.Components)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "10000", "key": "SPX-24", "self": "https://example.com/rest/api/2/issue/10000"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	card, err := c.CreateCard(context.Background(), model.NewCard{
+		Type:        "bug",
+		Title:       "bug-critical-12",
+		Description: "Fuel indicator not working",
+		Labels:      []string{"bug"},
+		Severity:    model.SeverityCritical,
+		Priority:    model.PriorityHigh,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "SPX-24", card.Id)
+	assert.Equal(t, server.URL+"/browse/SPX-24", card.Url)
+	assert.Equal(t, "SPX", card.BoardId)
+}
+
+func TestClient_CreateCard_Task(t *testing.T) {
+
+	// Jira API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req issue
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, "Task", req.Fields.IssueType.Name)
+		assert.Equal(t, []string{"research"}, req.Fields.Labels)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "10001", "key": "SPX-25"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	card, err := c.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Measure drag", Category: "Research"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "SPX-25", card.Id)
+}
+
+func TestClient_MoveCard(t *testing.T) {
+
+	// Jira API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue/SPX-24/transitions":
+			var req transition
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("error decoding request: %v", err)
+			}
+			assert.Equal(t, "31", req.Transition.Id)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/issue/SPX-24":
+			_, _ = w.Write([]byte(`{"id": "10000", "key": "SPX-24", "fields": {
+				"summary": "bug-critical-12",
+				"project": {"key": "SPX"},
+				"status": {"id": "3", "name": "In Progress"}}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	card, err := c.MoveCard(context.Background(), "SPX-24", "31")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, "3", card.ListId)
+	assert.Equal(t, "bug-critical-12", card.Title)
+}
+
+func TestClient_CreateCard_IssueType(t *testing.T) {
+
+	// Jira API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req issue
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		assert.Equal(t, "Incident", req.Fields.IssueType.Name)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": "10002", "key": "SPX-26"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	_, err := c.CreateCard(context.Background(), model.NewCard{Type: "incident", Title: "Telemetry lost", Description: "No telemetry since T+40s"})
+
+	assert.NoError(t, err)
+}
+
+func TestClient_CreateCard_Error(t *testing.T) {
+
+	// Jira API stand-in
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errorMessages": [], "errors": {"project": "project is required"}}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+
+	_, err := c.CreateCard(context.Background(), model.NewCard{Type: "issue", Title: "No pilot mode", Description: "Enable no pilot mode"})
+
+	assert.Error(t, err)
+}
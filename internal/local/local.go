@@ -0,0 +1,465 @@
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	boardId     = "local"
+	cardsPath   = "api/v1/cards"
+	fileMode    = 0600
+	openTimeout = 1 * time.Second
+)
+
+var cardsBucket = []byte("cards")
+
+// Store is the embedded BoltDB implementation of the service tracker backend.
+// It needs no network access, which makes it useful offline and in CI.
+type Store struct {
+	URL string
+	TaskIds
+	LabelIds
+	db *bolt.DB
+}
+
+type TaskIds struct {
+	ToDoListId string
+	BugLabelId string
+}
+
+type LabelIds struct {
+	CardTypes        []cfg.CardType
+	Categories       []cfg.Category
+	SeverityLabelIds map[string]string
+	PriorityLabelIds map[string]string
+}
+
+type record struct {
+	model.Card
+	Comments   []model.Comment   `json:"comments,omitempty"`
+	Checklists []model.Checklist `json:"checklists,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+func New(cfg cfg.Config) (*Store, error) {
+	if dir := filepath.Dir(cfg.LocalDBPath); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("error creating database directory, %w", err)
+		}
+	}
+
+	db, err := bolt.Open(cfg.LocalDBPath, fileMode, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error opening database %s, %w", cfg.LocalDBPath, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(cardsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("error creating cards bucket, %w", err)
+	}
+
+	s := Store{
+		URL: cfg.LocalBaseURL,
+		TaskIds: TaskIds{
+			ToDoListId: withDefault(cfg.ToDoListId, "todo"),
+			BugLabelId: withDefault(cfg.BugLabelId, "bug"),
+		},
+		LabelIds: LabelIds{
+			CardTypes:        cfg.CardTypesFor("local"),
+			Categories:       categories(cfg.CategoriesFor("local")),
+			SeverityLabelIds: cfg.SeverityLabelIds,
+			PriorityLabelIds: cfg.PriorityLabelIds,
+		},
+		db: db,
+	}
+	return &s, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// CreateCard stores the card with the labels of its type and those of its
+// category, severity and priority. Severities and priorities without a
+// configured label id are stored as "severity:<name>" and "priority:<name>".
+func (s *Store) CreateCard(ctx context.Context, request model.NewCard) (*model.Card, error) {
+	log.Printf("storing a %s in the local database with title: %s", request.Type, request.Title)
+	card := model.Card{
+		Title:       request.Title,
+		Description: request.Description,
+		ListId:      withDefault(request.ListId, s.ToDoListId),
+		Labels:      append([]string{}, request.Labels...),
+		Members:     request.Assignees,
+		Due:         request.Due,
+		Start:       request.Start,
+	}
+	if label := s.setLabel(request.Category); label != "" {
+		card.Labels = append(card.Labels, label)
+	}
+	if request.Severity != "" {
+		card.Labels = append(card.Labels, withDefault(s.SeverityLabelIds[request.Severity], "severity:"+request.Severity))
+	}
+	if request.Priority != "" {
+		card.Labels = append(card.Labels, withDefault(s.PriorityLabelIds[request.Priority], "priority:"+request.Priority))
+	}
+	if len(card.Labels) == 0 {
+		card.Labels = nil
+	}
+	return s.create(card)
+}
+
+func (s *Store) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	var r record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get(tx, id)
+		return err
+	})
+	if err != nil {
+		log.Printf("error while getting card %s", id)
+		return nil, err
+	}
+	return &r.Card, nil
+}
+
+func (s *Store) UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	return s.modify(id, func(card *model.Card) {
+		if update.Title != "" {
+			card.Title = update.Title
+		}
+		if update.Description != "" {
+			card.Description = update.Description
+		}
+		if len(update.Labels) > 0 {
+			card.Labels = update.Labels
+		}
+		if update.ListId != "" {
+			card.ListId = update.ListId
+		}
+	})
+}
+
+func (s *Store) ArchiveCard(ctx context.Context, id string) (*model.Card, error) {
+	return s.modify(id, func(card *model.Card) {
+		card.Closed = true
+	})
+}
+
+func (s *Store) MoveCard(ctx context.Context, id string, listId string) (*model.Card, error) {
+	return s.UpdateCard(ctx, id, model.CardUpdate{ListId: listId})
+}
+
+func (s *Store) DeleteCard(ctx context.Context, id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if _, err := get(tx, id); err != nil {
+			return err
+		}
+		return tx.Bucket(cardsBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		log.Printf("error while deleting card %s", id)
+		return err
+	}
+	return nil
+}
+
+func (s *Store) AddComment(ctx context.Context, cardId string, text string) (*model.Comment, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	comment := model.Comment{Id: id, Text: text, CreatedAt: time.Now().UTC()}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		r, err := get(tx, cardId)
+		if err != nil {
+			return err
+		}
+		r.Comments = append(r.Comments, comment)
+		return put(tx, r)
+	})
+	if err != nil {
+		log.Printf("error while adding a comment to card %s", cardId)
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListComments returns the comments of the card, newest first as Trello does.
+func (s *Store) ListComments(ctx context.Context, cardId string) ([]model.Comment, error) {
+	var r record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get(tx, cardId)
+		return err
+	})
+	if err != nil {
+		log.Printf("error while getting the comments of card %s", cardId)
+		return nil, err
+	}
+
+	comments := make([]model.Comment, 0, len(r.Comments))
+	for i := len(r.Comments) - 1; i >= 0; i-- {
+		comments = append(comments, r.Comments[i])
+	}
+	return comments, nil
+}
+
+func (s *Store) AddChecklist(ctx context.Context, cardId string, name string, items []string) (*model.Checklist, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+	checklist := model.Checklist{Id: id, Name: name, Items: []model.CheckItem{}}
+	for _, item := range items {
+		itemId, err := newId()
+		if err != nil {
+			return nil, err
+		}
+		checklist.Items = append(checklist.Items, model.CheckItem{Id: itemId, Name: item, State: model.CheckItemIncomplete})
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		r, err := get(tx, cardId)
+		if err != nil {
+			return err
+		}
+		r.Checklists = append(r.Checklists, checklist)
+		return put(tx, r)
+	})
+	if err != nil {
+		log.Printf("error while adding a checklist to card %s", cardId)
+		return nil, err
+	}
+	return &checklist, nil
+}
+
+func (s *Store) ListChecklists(ctx context.Context, cardId string) ([]model.Checklist, error) {
+	var r record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get(tx, cardId)
+		return err
+	})
+	if err != nil {
+		log.Printf("error while getting the checklists of card %s", cardId)
+		return nil, err
+	}
+	if r.Checklists == nil {
+		return []model.Checklist{}, nil
+	}
+	return r.Checklists, nil
+}
+
+func (s *Store) SetCheckItem(ctx context.Context, cardId string, itemId string, complete bool) (*model.CheckItem, error) {
+	state := model.CheckItemIncomplete
+	if complete {
+		state = model.CheckItemComplete
+	}
+
+	var item *model.CheckItem
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		r, err := get(tx, cardId)
+		if err != nil {
+			return err
+		}
+		for i := range r.Checklists {
+			for j := range r.Checklists[i].Items {
+				if r.Checklists[i].Items[j].Id == itemId {
+					r.Checklists[i].Items[j].State = state
+					item = &r.Checklists[i].Items[j]
+				}
+			}
+		}
+		if item == nil {
+			return fmt.Errorf("error: check item %s, %w", itemId, model.ErrNotFound)
+		}
+		return put(tx, r)
+	})
+	if err != nil {
+		log.Printf("error while updating check item %s", itemId)
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *Store) ListCards(ctx context.Context, query model.CardQuery) ([]model.Card, error) {
+	var records []record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(cardsBucket).ForEach(func(_, v []byte) error {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("error unmarshaling card, %w", err)
+			}
+			if s.matches(r.Card, query) {
+				records = append(records, r)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("error while listing cards")
+		return nil, err
+	}
+
+	// Oldest first, so pages stay stable while new cards are added
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	cards := make([]model.Card, 0, len(records))
+	for _, r := range records {
+		cards = append(cards, r.Card)
+	}
+	return cards, nil
+}
+
+func (s *Store) create(card model.Card) (*model.Card, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+
+	card.Id = id
+	card.BoardId = boardId
+	card.Url = fmt.Sprintf("%s/%s/%s", s.URL, cardsPath, id)
+
+	now := time.Now().UTC()
+	r := record{Card: card, CreatedAt: now, UpdatedAt: now}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, r)
+	})
+	if err != nil {
+		log.Printf("error while storing card")
+		return nil, err
+	}
+	return &r.Card, nil
+}
+
+func (s *Store) modify(id string, change func(card *model.Card)) (*model.Card, error) {
+	var r record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		r, err = get(tx, id)
+		if err != nil {
+			return err
+		}
+		change(&r.Card)
+		r.UpdatedAt = time.Now().UTC()
+		return put(tx, r)
+	})
+	if err != nil {
+		log.Printf("error while updating card %s", id)
+		return nil, err
+	}
+	return &r.Card, nil
+}
+
+func get(tx *bolt.Tx, id string) (record, error) {
+	var r record
+	v := tx.Bucket(cardsBucket).Get([]byte(id))
+	if v == nil {
+		return r, fmt.Errorf("error: card %s, %w", id, model.ErrNotFound)
+	}
+	if err := json.Unmarshal(v, &r); err != nil {
+		return r, fmt.Errorf("error unmarshaling card %s, %w", id, err)
+	}
+	return r, nil
+}
+
+func put(tx *bolt.Tx, r record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("error marshaling card %s, %w", r.Id, err)
+	}
+	return tx.Bucket(cardsBucket).Put([]byte(r.Id), b)
+}
+
+// newId returns a random 24 characters hex id, the same shape Trello uses.
+func newId() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating card id, %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Store) matches(card model.Card, query model.CardQuery) bool {
+	if card.Closed || (query.ListId != "" && card.ListId != query.ListId) {
+		return false
+	}
+	if query.Category != "" && !hasLabel(card.Labels, s.setLabel(query.Category)) {
+		return false
+	}
+	if query.Type != "" && query.Type != s.cardType(card.Labels) {
+		return false
+	}
+	text := strings.ToLower(query.Text)
+	return text == "" ||
+		strings.Contains(strings.ToLower(card.Title), text) ||
+		strings.Contains(strings.ToLower(card.Description), text)
+}
+
+func (s *Store) cardType(labels []string) string {
+	return cfg.TypeOf(s.CardTypes, labels, s.hasCategory(labels))
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// setLabel returns the label of the category, matched by name or alias.
+func (s *Store) setLabel(category string) string {
+	v, _ := cfg.FindCategory(s.Categories, category)
+	return v.Label
+}
+
+func (s *Store) hasCategory(labels []string) bool {
+	for _, v := range s.Categories {
+		if hasLabel(labels, v.Label) {
+			return true
+		}
+	}
+	return false
+}
+
+// categories labels the categories without a label with their lower case name.
+func categories(categories []cfg.Category) []cfg.Category {
+	labelled := make([]cfg.Category, len(categories))
+	for i, c := range categories {
+		c.Label = withDefault(c.Label, withDefault(c.LabelName, strings.ToLower(c.Name)))
+		labelled[i] = c
+	}
+	return labelled
+}
+
+func withDefault(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
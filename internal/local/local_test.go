@@ -0,0 +1,172 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T) *Store {
+	s, err := New(cfg.Config{
+		ToDoListId: "1",
+		Local: cfg.Local{
+			LocalDBPath:  filepath.Join(t.TempDir(), "cards.db"),
+			LocalBaseURL: "http://localhost:3000",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestStore_CreateAndGetCard(t *testing.T) {
+	s := newTestStore(t)
+
+	card, err := s.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Keys cleaning", Category: "Maintenance"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, card.Id, 24)
+	assert.Equal(t, "http://localhost:3000/api/v1/cards/"+card.Id, card.Url)
+	assert.Equal(t, "local", card.BoardId)
+	assert.Equal(t, "1", card.ListId)
+	assert.Equal(t, []string{"maintenance"}, card.Labels)
+
+	got, err := s.GetCard(context.Background(), card.Id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, card, got)
+}
+
+func TestStore_UpdateAndMoveCard(t *testing.T) {
+	s := newTestStore(t)
+
+	card, err := s.CreateCard(context.Background(), model.NewCard{
+		Type:        "bug",
+		Title:       "bug-critical-12",
+		Description: "Fuel indicator",
+		ListId:      "doing",
+		Labels:      []string{"bug"},
+		Severity:    model.SeverityCritical,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "doing", card.ListId)
+	assert.Equal(t, []string{"bug", "severity:critical"}, card.Labels)
+
+	updated, err := s.UpdateCard(context.Background(), card.Id, model.CardUpdate{Description: "Fuel level indicator stuck"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "bug-critical-12", updated.Title)
+	assert.Equal(t, "Fuel level indicator stuck", updated.Description)
+
+	moved, err := s.MoveCard(context.Background(), card.Id, "done")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "done", moved.ListId)
+}
+
+func TestStore_DeleteCard(t *testing.T) {
+	s := newTestStore(t)
+
+	card, err := s.CreateCard(context.Background(), model.NewCard{Type: "issue", Title: "No pilot mode", Description: "Enable it"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.NoError(t, s.DeleteCard(context.Background(), card.Id))
+
+	_, err = s.GetCard(context.Background(), card.Id)
+	assert.Error(t, err)
+	assert.Error(t, s.DeleteCard(context.Background(), card.Id))
+}
+
+func TestStore_ListCards(t *testing.T) {
+	s := newTestStore(t)
+
+	bug, _ := s.CreateCard(context.Background(), model.NewCard{Type: "bug", Title: "bug-critical-12", Description: "Fuel indicator", Labels: []string{"bug"}})
+	task, _ := s.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Refill fuel", Category: "Maintenance"})
+	_, _ = s.CreateCard(context.Background(), model.NewCard{Type: "issue", Title: "No pilot mode", Description: "Enable it"})
+
+	cards, err := s.ListCards(context.Background(), model.CardQuery{Text: "FUEL"})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Card{*bug, *task}, cards)
+
+	cards, err = s.ListCards(context.Background(), model.CardQuery{Category: "Maintenance"})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Card{*task}, cards)
+
+	cards, err = s.ListCards(context.Background(), model.CardQuery{Type: "issue"})
+	assert.NoError(t, err)
+	assert.Len(t, cards, 1)
+	assert.Equal(t, "No pilot mode", cards[0].Title)
+}
+
+func TestStore_ListCards_ConfiguredType(t *testing.T) {
+	s := newTestStore(t)
+	s.CardTypes = append(cfg.DefaultCardTypes(cfg.Config{Tracker: "local"}),
+		cfg.CardType{Name: "incident", Labels: []string{"bug", "incident"}})
+
+	incident, _ := s.CreateCard(context.Background(), model.NewCard{Type: "incident", Title: "Outage", Labels: []string{"bug", "incident"}})
+	bug, _ := s.CreateCard(context.Background(), model.NewCard{Type: "bug", Title: "bug-critical-12", Labels: []string{"bug"}})
+
+	cards, err := s.ListCards(context.Background(), model.CardQuery{Type: "incident"})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Card{*incident}, cards)
+
+	cards, err = s.ListCards(context.Background(), model.CardQuery{Type: "bug"})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Card{*bug}, cards)
+}
+
+func TestStore_Comments(t *testing.T) {
+	s := newTestStore(t)
+
+	card, _ := s.CreateCard(context.Background(), model.NewCard{Type: "bug", Title: "bug-critical-12", Description: "Fuel indicator", Labels: []string{"bug"}})
+
+	_, err := s.AddComment(context.Background(), card.Id, "Looking into it")
+	assert.NoError(t, err)
+	_, err = s.AddComment(context.Background(), card.Id, "Fix deployed")
+	assert.NoError(t, err)
+
+	comments, err := s.ListComments(context.Background(), card.Id)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 2)
+	assert.Equal(t, "Fix deployed", comments[0].Text)
+
+	_, err = s.AddComment(context.Background(), "missing", "Hello")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestStore_Checklists(t *testing.T) {
+	s := newTestStore(t)
+
+	card, _ := s.CreateCard(context.Background(), model.NewCard{Type: "task", Title: "Refill oil", Category: "Maintenance"})
+
+	checklist, err := s.AddChecklist(context.Background(), card.Id, "Checklist", []string{"Drain old oil", "Refill oil"})
+	assert.NoError(t, err)
+	assert.Len(t, checklist.Items, 2)
+
+	item, err := s.SetCheckItem(context.Background(), card.Id, checklist.Items[0].Id, true)
+	assert.NoError(t, err)
+	assert.Equal(t, model.CheckItemComplete, item.State)
+
+	checklists, err := s.ListChecklists(context.Background(), card.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, model.CheckItemComplete, checklists[0].Items[0].State)
+	assert.Equal(t, model.CheckItemIncomplete, checklists[0].Items[1].State)
+
+	_, err = s.SetCheckItem(context.Background(), card.Id, "missing", true)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
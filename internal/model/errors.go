@@ -0,0 +1,92 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrNotFound       = errors.New("card not found")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrUnsupported    = errors.New("operation not supported by the tracker backend")
+	// ErrUnavailable is a tracker that cannot be reached or refuses the
+	// request for now, ErrTimeout one that did not answer in time.
+	ErrUnavailable = errors.New("tracker unavailable")
+	ErrTimeout     = errors.New("tracker timed out")
+	// ErrUpstream is a tracker that answered with any other error.
+	ErrUpstream = errors.New("tracker error")
+)
+
+// UpstreamError is the error of a tracker response that was not successful.
+func UpstreamError(code int) error {
+	switch code {
+	case http.StatusNotFound:
+		return fmt.Errorf("error returned from external API, %w", ErrNotFound)
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return fmt.Errorf("error returned from external API, code %d: %w", code, ErrUnavailable)
+	case http.StatusGatewayTimeout:
+		return fmt.Errorf("error returned from external API, code %d: %w", code, ErrTimeout)
+	default:
+		return fmt.Errorf("error returned from external API, code %d: %w", code, ErrUpstream)
+	}
+}
+
+type shortTimeoutKey struct{}
+
+// WithShortTimeout marks the requests whose caller asked for less time than
+// the default timeout, so their timeouts say nothing about the tracker.
+func WithShortTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, shortTimeoutKey{}, true)
+}
+
+// ShortTimeout tells whether the caller asked for less time than the
+// default timeout.
+func ShortTimeout(ctx context.Context) bool {
+	short, _ := ctx.Value(shortTimeoutKey{}).(bool)
+	return short
+}
+
+// TransportError is the error of a tracker request that got no response.
+// Requests cancelled because the caller gave up or ran out of time are
+// timeouts. The request URL is left out, as it can carry the credentials of
+// the tracker.
+func TransportError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("error sending request, %v: %w", err, ErrTimeout)
+	}
+	return fmt.Errorf("error sending request, %v: %w", err, ErrUnavailable)
+}
+
+// FieldError is a request field that failed validation, e.g. "links[1]", and why.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError lists every invalid field of a request. It matches
+// ErrInvalidRequest with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		reasons[i] = f.Field + " " + f.Reason
+	}
+	return "invalid request: " + strings.Join(reasons, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidRequest
+}
@@ -1,32 +1,150 @@
 package model
 
+import (
+	"io"
+	"time"
+)
+
 type MasterTask struct {
-	Type        string `json:"type,omitempty"`
-	Title       string `json:"title,omitempty"`
-	Description string `json:"description,omitempty"`
-	Category    string `json:"category,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Checklist   []string `json:"checklist,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Start       string   `json:"start,omitempty"`
+	Reminder    string   `json:"reminder,omitempty"`
+	Assignees   []string `json:"assignees,omitempty"`
+	Severity    string   `json:"severity,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Reporter    string   `json:"reporter,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Steps       []string `json:"steps,omitempty"`
+	Links       []string `json:"links,omitempty"`
+	// Fields are custom fields set by name on the card, e.g. "customer".
+	Fields map[string]interface{} `json:"fields,omitempty"`
 }
 
-type Issue struct {
+// NewCard is a card of any configured type, its title and description
+// already rendered and its list and labels resolved from the card type.
+// Assignees are emails or tracker usernames, each tracker resolves them to
+// its own member ids.
+type NewCard struct {
 	Type        string
 	Title       string
 	Description string
+	ListId      string
+	Labels      []string
+	Category    string
+	Severity    string
+	Priority    string
+	Assignees   []string
+	Schedule
 }
 
-type Bug struct {
-	Type        string
-	Description string
+// Schedule holds the optional dates of a card. Reminder is how long before
+// the due date the members are notified.
+type Schedule struct {
+	Due      *time.Time
+	Start    *time.Time
+	Reminder time.Duration
+}
+
+type Card struct {
+	Id          string     `json:"id"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Url         string     `json:"url"`
+	BoardId     string     `json:"board_id"`
+	ListId      string     `json:"list_id"`
+	Labels      []string   `json:"labels,omitempty"`
+	Members     []string   `json:"members,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	Start       *time.Time `json:"start,omitempty"`
+	Closed      bool       `json:"closed"`
 }
 
-type Task struct {
+// CardUpdate holds the card attributes to change. Empty fields are left untouched.
+type CardUpdate struct {
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	ListId      string   `json:"list_id,omitempty"`
+}
+
+// Transition names the workflow state a card should be moved to.
+type Transition struct {
+	State string `json:"state"`
+}
+
+// CardQuery filters the cards returned by a listing. Cursor and Limit are
+// handled by the service, trackers only apply the filters.
+type CardQuery struct {
 	Type     string
-	Title    string
 	Category string
+	State    string
+	ListId   string
+	Text     string
+	Cursor   string
+	Limit    int
 }
 
-type Card struct {
-	Id      string `json:"id"`
-	Url     string `json:"url"`
-	BoardId string `json:"idBoard"`
-	ListId  string `json:"idList"`
+type CardList struct {
+	Cards      []Card `json:"cards"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type Comment struct {
+	Id        string    `json:"id,omitempty"`
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Attachment struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Url      string `json:"url"`
+	Bytes    int64  `json:"bytes"`
+	MimeType string `json:"mime_type"`
+}
+
+// File is an upload to be attached to a card.
+type File struct {
+	Name     string
+	MimeType string
+	Content  io.Reader
+}
+
+type Checklist struct {
+	Id    string      `json:"id"`
+	Name  string      `json:"name"`
+	Items []CheckItem `json:"items"`
+}
+
+type CheckItem struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
 }
+
+// Check item states.
+const (
+	CheckItemComplete   = "complete"
+	CheckItemIncomplete = "incomplete"
+)
+
+// Bug severities, critical bugs go straight to the doing list.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Bug priorities.
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
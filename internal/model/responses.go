@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+// Welcome is the response of the welcome endpoint.
+type Welcome struct {
+	Message string `json:"message"`
+}
+
+// CardCreated is the response of a created card. The optional fields are
+// only set when the card has them.
+type CardCreated struct {
+	Message     string     `json:"message"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Id          string     `json:"id"`
+	Url         string     `json:"url"`
+	BoardId     string     `json:"board_id"`
+	ListId      string     `json:"list_id"`
+	Category    string     `json:"category,omitempty"`
+	Severity    string     `json:"severity,omitempty"`
+	Priority    string     `json:"priority,omitempty"`
+	ChecklistId string     `json:"checklist_id,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	Start       *time.Time `json:"start,omitempty"`
+}
+
+// CommentList is the response of the comments of a card.
+type CommentList struct {
+	Comments []Comment `json:"comments"`
+}
+
+// ChecklistList is the response of the checklists of a card.
+type ChecklistList struct {
+	Checklists []Checklist `json:"checklists"`
+}
+
+// Problem is an RFC 7807 problem details response. Errors lists the invalid
+// fields of validation problems.
+type Problem struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	CorrelationId string       `json:"correlation_id"`
+	Errors        []FieldError `json:"errors,omitempty"`
+}
+
+// Health is the response of the health endpoint. The status is ok,
+// degraded while the tracker circuit is probing, or unavailable while it is
+// open.
+type Health struct {
+	Status  string        `json:"status"`
+	Tracker TrackerHealth `json:"tracker"`
+}
+
+// TrackerHealth is the state of the circuit breaker of the tracker backend.
+// Failures are the failed calls in a row, and RetryAt when an open circuit
+// lets calls through again.
+type TrackerHealth struct {
+	Name     string     `json:"name"`
+	Circuit  string     `json:"circuit"`
+	Failures int        `json:"failures"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+const (
+	HealthOk          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
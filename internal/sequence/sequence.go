@@ -0,0 +1,57 @@
+package sequence
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	fileMode    = 0600
+	openTimeout = 1 * time.Second
+)
+
+// Store persists named counters, e.g. the bug numbers, so they keep growing
+// across restarts whatever tracker backend is in use.
+type Store struct {
+	db *bolt.DB
+}
+
+func New(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("error creating sequence directory, %w", err)
+		}
+	}
+
+	db, err := bolt.Open(path, fileMode, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error opening sequence database %s, %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Next returns the next value of the named counter, starting at 1. Values
+// are never handed out twice, even when the caller fails to use them.
+func (s *Store) Next(name string) (uint64, error) {
+	var n uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+		n, err = b.NextSequence()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting next %s number, %w", name, err)
+	}
+	return n, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
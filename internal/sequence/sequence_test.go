@@ -0,0 +1,36 @@
+package sequence
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Next(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sequence.db")
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for want := uint64(1); want <= 3; want++ {
+		n, err := s.Next("bug")
+		assert.NoError(t, err)
+		assert.Equal(t, want, n)
+	}
+	n, _ := s.Next("incident")
+	assert.Equal(t, uint64(1), n)
+	assert.NoError(t, s.Close())
+
+	// The counters survive a restart
+	s, err = New(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	n, err = s.Next("bug")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), n)
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const summaryLength = 50
+
+// cardType is a configured card type with its templates parsed.
+type cardType struct {
+	cfg.CardType
+	title       *template.Template
+	description *template.Template
+}
+
+// cardData is what the title and description templates are executed with.
+// The card is only numbered when a template uses Number or Id.
+type cardData struct {
+	Type        string
+	Title       string
+	Description string
+	Category    string
+	Severity    string
+	Priority    string
+	Summary     string
+	Reporter    string
+	Environment string
+	Steps       []string
+	Links       []string
+	Assignees   []string
+	Due         string
+	Fields      map[string]interface{}
+	sequence    Sequencer
+	number      uint64
+}
+
+// Number is the next value of the card type sequence, e.g. 23.
+func (d *cardData) Number() (uint64, error) {
+	if d.number == 0 {
+		n, err := d.sequence.Next(d.Type)
+		if err != nil {
+			return 0, err
+		}
+		d.number = n
+	}
+	return d.number, nil
+}
+
+// Id is the number prefixed with the card type, e.g. "BUG-0023".
+func (d *cardData) Id() (string, error) {
+	n, err := d.Number()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%04d", strings.ToUpper(d.Type), n), nil
+}
+
+// newCardTypes parses the templates of the card types, which were already
+// validated when the config was loaded.
+func newCardTypes(types []cfg.CardType) map[string]cardType {
+	m := make(map[string]cardType, len(types))
+	for _, t := range types {
+		m[t.Name] = cardType{
+			CardType:    t,
+			title:       template.Must(cfg.ParseTemplate("title", t.Title)),
+			description: template.Must(cfg.ParseTemplate("description", t.Description)),
+		}
+	}
+	return m
+}
+
+// makeCard renders the title and description of the card and resolves its
+// list, routing it by severity when the card type says so.
+func (s *TaskService) makeCard(cardType cardType, task model.MasterTask, severity, priority string) (model.NewCard, error) {
+	data := &cardData{
+		Type:        cardType.Name,
+		Title:       strings.TrimSpace(task.Title),
+		Description: task.Description,
+		Category:    task.Category,
+		Severity:    severity,
+		Priority:    priority,
+		Summary:     summarize(task.Description),
+		Reporter:    strings.TrimSpace(task.Reporter),
+		Environment: strings.TrimSpace(task.Environment),
+		Steps:       task.Steps,
+		Links:       task.Links,
+		Assignees:   task.Assignees,
+		Due:         task.Due,
+		Fields:      task.Fields,
+		sequence:    s.sequence,
+	}
+
+	title, err := render(cardType.title, data)
+	if err != nil {
+		return model.NewCard{}, err
+	}
+	if title == "" {
+		return model.NewCard{}, fmt.Errorf("empty title for %s card: %w", cardType.Name, model.ErrInvalidRequest)
+	}
+	description, err := render(cardType.description, data)
+	if err != nil {
+		return model.NewCard{}, err
+	}
+
+	state := cardType.List
+	if routed, ok := cardType.SeverityLists[severity]; ok {
+		state = routed
+	}
+	// The states were checked when the config was loaded. Jira and GitHub
+	// have no lists, their issues start in their initial status.
+	listId := s.workflow[strings.ToLower(state)]
+
+	return model.NewCard{
+		Type:        cardType.Name,
+		Title:       title,
+		Description: description,
+		ListId:      listId,
+		Labels:      cardType.Labels,
+		Category:    task.Category,
+		Severity:    severity,
+		Priority:    priority,
+		Assignees:   task.Assignees,
+	}, nil
+}
+
+// newCategories indexes the categories by their lower case names and aliases,
+// which were already checked to be unique when the config was loaded.
+func newCategories(categories []cfg.Category) map[string]cfg.Category {
+	m := make(map[string]cfg.Category, len(categories))
+	for _, c := range categories {
+		for _, name := range append([]string{c.Name}, c.Aliases...) {
+			m[strings.ToLower(strings.TrimSpace(name))] = c
+		}
+	}
+	return m
+}
+
+// category finds the category by its name or any of its aliases, ignoring case.
+func (s *TaskService) category(name string) (cfg.Category, bool) {
+	c, ok := s.categories[strings.ToLower(strings.TrimSpace(name))]
+	return c, ok
+}
+
+func (s *TaskService) typeNames() []string {
+	names := make([]string, 0, len(s.cardTypes))
+	for name := range s.cardTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func render(t *template.Template, data *cardData) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("error rendering card %s, %w", t.Name(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// summarize keeps the first words of the first line of the description,
+// cutting them at summaryLength characters.
+func summarize(description string) string {
+	line := strings.TrimSpace(description)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	summary := ""
+	for _, word := range strings.Fields(line) {
+		next := word
+		if summary != "" {
+			next = summary + " " + word
+		}
+		if utf8.RuneCountInString(next) > summaryLength {
+			if summary == "" {
+				summary = string([]rune(word)[:summaryLength])
+			}
+			return summary + "..."
+		}
+		summary = next
+	}
+	return summary
+}
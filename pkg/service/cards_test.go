@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var incidentType = cfg.CardType{
+	Name:        "incident",
+	Required:    []string{"description", "severity"},
+	List:        "doing",
+	Labels:      []string{"77"},
+	Title:       "{{.Id}} {{.Summary}}",
+	Description: "Severity: {{.Severity}}\n\n{{.Description}}",
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, "Fuel gauge stuck at half tank", summarize("  Fuel gauge stuck at half tank\nSeen on SN-24"))
+	assert.Equal(t, "The fuel gauge on the main dashboard stays stuck...",
+		summarize("The fuel gauge on the main dashboard stays stuck at half tank after refuelling"))
+	assert.Equal(t, "", summarize(""))
+}
+
+func TestTaskService_FilterTask_CustomType(t *testing.T) {
+	tracker := new(MockTracker)
+	config := cfg.Config{Workflow: map[string]string{"todo": "1", "doing": "2"}}
+	config.CardTypes = append(cfg.DefaultCardTypes(config), incidentType)
+	srv := New(tracker, new(counter), config)
+
+	tracker.On("CreateCard", model.NewCard{
+		Type:        "incident",
+		Title:       "INCIDENT-0001 Telemetry lost",
+		Description: "Severity: high\n\nTelemetry lost",
+		ListId:      "2",
+		Labels:      []string{"77"},
+		Severity:    model.SeverityHigh,
+	}).Once().Return(testCard, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "Incident", Description: "Telemetry lost", Severity: "high"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "INCIDENT-0001 Telemetry lost", res.Title)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_MissingRequired(t *testing.T) {
+	tracker := new(MockTracker)
+	seq := new(counter)
+	srv := New(tracker, seq, cfg.Config{CardTypes: []cfg.CardType{incidentType}})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "incident", Description: "Telemetry lost"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	assert.Equal(t, &model.ValidationError{Fields: []model.FieldError{{Field: "severity", Reason: "is required"}}}, err)
+	assert.Zero(t, seq.n)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_UnknownType(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "spike", Title: "Try ion engines"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	assert.Contains(t, err.Error(), "bug, issue, task")
+}
+
+func TestTaskService_FilterTask_BugTitleTemplate(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{BugTitleTemplate: "[{{.Severity}}] #{{.Number}} {{.Summary}}"})
+
+	tracker.On("CreateCard", mock.MatchedBy(func(card model.NewCard) bool {
+		return card.Title == "[critical] #1 Fuel gauge stuck"
+	})).Once().Return(testCard, nil)
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Fuel gauge stuck", Severity: "critical"})
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_NotNumbered(t *testing.T) {
+	tracker := new(MockTracker)
+	seq := new(counter)
+	srv := New(tracker, seq, cfg.Config{})
+
+	tracker.On("CreateCard", mock.Anything).Once().Return(testCard, nil)
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "issue", Title: "No pilot mode", Description: "Enable it"})
+
+	// Issue titles do not use the sequence, so no number is taken
+	assert.NoError(t, err)
+	assert.Zero(t, seq.n)
+}
+
+func TestTaskService_FilterTask_CategoryAlias(t *testing.T) {
+	tracker := new(MockTracker)
+	config := cfg.Config{Categories: []cfg.Category{
+		{Name: "Maintenance", Aliases: []string{"maint", "upkeep"}},
+		{Name: "Security"},
+	}}
+	config.CardTypes = append(cfg.DefaultCardTypes(config),
+		cfg.CardType{Name: "audit", Required: []string{"title"}, List: "todo", Title: "{{.Title}}", Description: "{{.Category}}", Categories: []string{"Security"}})
+	srv := New(tracker, new(counter), config)
+
+	tracker.On("CreateCard", mock.MatchedBy(func(card model.NewCard) bool {
+		return card.Category == "Maintenance" && card.Description == "Belongs to category Maintenance"
+	})).Once().Return(testCard, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "task", Title: "Refill oil", Category: "UPKEEP"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Maintenance", res.Category)
+	tracker.AssertExpectations(t)
+
+	_, err = srv.FilterTask(context.Background(), model.MasterTask{Type: "audit", Title: "Rotate keys", Category: "maint"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNumberOfCalls(t, "CreateCard", 1)
+}
+
+func TestTaskService_FilterTask_MarkdownDescription(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	tracker.On("CreateCard", mock.MatchedBy(func(card model.NewCard) bool {
+		return card.Description == "Fuel gauge stuck at half tank\n\n"+
+			"### Environment\nSN-24, firmware 2.1\n\n"+
+			"### Steps to reproduce\n1. Refuel\n2. Start the engine\n\n"+
+			"### Links\n- https://example.com/logs/42\n\n"+
+			"Reported by dana@example.com"
+	})).Once().Return(testCard, nil)
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{
+		Type:        "bug",
+		Description: "Fuel gauge stuck at half tank",
+		Environment: "SN-24, firmware 2.1",
+		Steps:       []string{"Refuel", "Start the engine"},
+		Links:       []string{"https://example.com/logs/42"},
+		Reporter:    "dana@example.com",
+	})
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_InvalidLink(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Fuel gauge stuck", Links: []string{"logs/42"}})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_FieldErrors(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{
+		Type:     "task",
+		Title:    strings.Repeat("a", 513),
+		Category: "Cooking",
+		Priority: "urgent",
+		Links:    []string{"https://example.com", "logs/42"},
+		Steps:    []string{""},
+	})
+
+	var validation *model.ValidationError
+	assert.ErrorAs(t, err, &validation)
+	assert.Equal(t, []model.FieldError{
+		{Field: "title", Reason: "must be at most 512 characters, got 513"},
+		{Field: "category", Reason: "must be one of Maintenance, Research, Test"},
+		{Field: "priority", Reason: "must be one of high, medium, low"},
+		{Field: "steps[0]", Reason: "must not be empty"},
+		{Field: "links[1]", Reason: "must be an http or https URL"},
+	}, validation.Fields)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_DateErrors(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "issue", Description: "y", Due: "tomorrow", Reminder: "soon"})
+
+	var validation *model.ValidationError
+	assert.ErrorAs(t, err, &validation)
+	assert.Equal(t, []model.FieldError{
+		{Field: "title", Reason: "is required"},
+		{Field: "due", Reason: "must be an RFC 3339 date or an offset like +3d"},
+		{Field: "reminder", Reason: "must be an offset like 30m, 2h or 1d"},
+	}, validation.Fields)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_Schema_AcceptedRequest(t *testing.T) {
+	tracker := new(MockTracker)
+	tracker.On("CreateCard", mock.Anything).Once().Return(testCard, nil)
+	srv := New(tracker, new(counter), cfg.Config{})
+	masterTask := model.MasterTask{Type: "Bug", Description: "Fuel gauge stuck", Severity: "Critical", Priority: "HIGH"}
+
+	_, err := srv.FilterTask(context.Background(), masterTask)
+	assert.NoError(t, err)
+
+	// The values the service accepted match the published schema
+	schema, err := srv.Schema("bug")
+	assert.NoError(t, err)
+	properties := schema["properties"].(map[string]interface{})
+	for field, value := range map[string]string{"type": masterTask.Type, "severity": masterTask.Severity, "priority": masterTask.Priority} {
+		pattern := properties[field].(map[string]interface{})["pattern"].(string)
+		assert.Regexp(t, regexp.MustCompile(pattern), value, field)
+	}
+	assert.NotRegexp(t, regexp.MustCompile(properties["severity"].(map[string]interface{})["pattern"].(string)), "urgent")
+}
+
+func TestTaskService_Schema(t *testing.T) {
+	srv := New(new(MockTracker), new(counter), cfg.Config{})
+
+	schema, err := srv.Schema("Task")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1/schemas/task", schema["$id"])
+	assert.Equal(t, []string{"category", "title", "type"}, schema["required"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"description": "Card title",
+		"type":        "string",
+		"maxLength":   512,
+		"minLength":   1,
+	}, properties["title"])
+	assert.Equal(t, []string{"Maintenance", "Research", "Test"}, properties["category"].(map[string]interface{})["examples"])
+	assert.Equal(t, "^(?:[Mm][Aa][Ii][Nn][Tt][Ee][Nn][Aa][Nn][Cc][Ee]|[Rr][Ee][Ss][Ee][Aa][Rr][Cc][Hh]|[Tt][Ee][Ss][Tt])$", properties["category"].(map[string]interface{})["pattern"])
+
+	_, err = srv.Schema("spike")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const day = 24 * time.Hour
+
+// maxOffset bounds the relative dates and reminders, so the offsets cannot
+// overflow.
+const maxOffset = 5 * 365 * day
+
+// relativeDate matches offsets from now such as "+3d", "+12h" or "+2w".
+var relativeDate = regexp.MustCompile(`^\+(\d+)([mhdw])$`)
+
+// offset matches reminder offsets before the due date such as "30m" or "1d".
+var offset = regexp.MustCompile(`^(\d+)([mhdw])$`)
+
+var units = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": day,
+	"w": 7 * day,
+}
+
+// makeSchedule parses and validates the due, start and reminder fields of the
+// request, reporting every invalid one at once.
+func makeSchedule(masterTask model.MasterTask, now time.Time) (model.Schedule, error) {
+	var schedule model.Schedule
+
+	due, dueErr := parseDate("due", masterTask.Due, now)
+	start, startErr := parseDate("start", masterTask.Start, now)
+	errs := []error{dueErr, startErr}
+	if due != nil && start != nil && start.After(*due) {
+		log.Printf("error %+v. 'start' after 'due'", http.StatusBadRequest)
+		errs = append(errs, fieldError("start", fmt.Sprintf("must not be after due %s", due.Format(time.RFC3339))))
+	}
+
+	if masterTask.Reminder != "" {
+		reminder, err := parseReminder(masterTask.Reminder, masterTask.Due)
+		errs = append(errs, err)
+		schedule.Reminder = reminder
+	}
+
+	if err := mergeFieldErrors(errs...); err != nil {
+		return model.Schedule{}, err
+	}
+	schedule.Due = due
+	schedule.Start = start
+	return schedule, nil
+}
+
+// parseReminder accepts offsets before the due date like "30m" or "1d".
+func parseReminder(value string, due string) (time.Duration, error) {
+	if due == "" {
+		log.Printf("error %+v. 'reminder' without 'due'", http.StatusBadRequest)
+		return 0, fieldError("reminder", "needs a due date")
+	}
+	m := offset.FindStringSubmatch(value)
+	if m == nil {
+		log.Printf("error %+v. invalid 'reminder' field", http.StatusBadRequest)
+		return 0, fieldError("reminder", "must be an offset like 30m, 2h or 1d")
+	}
+	reminder, ok := parseOffset(m[1], m[2])
+	if !ok {
+		log.Printf("error %+v. 'reminder' too large", http.StatusBadRequest)
+		return 0, fieldError("reminder", fmt.Sprintf("must be at most %dd", maxOffset/day))
+	}
+	return reminder, nil
+}
+
+// parseDate accepts RFC 3339 timestamps, plain dates and offsets from now like "+3d".
+func parseDate(field string, value string, now time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if m := relativeDate.FindStringSubmatch(value); m != nil {
+		d, ok := parseOffset(m[1], m[2])
+		if !ok {
+			log.Printf("error %+v. '%s' too far in the future", http.StatusBadRequest, field)
+			return nil, fieldError(field, fmt.Sprintf("must be at most +%dd from now", maxOffset/day))
+		}
+		t := now.Add(d).UTC().Truncate(time.Minute)
+		return &t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return &t, nil
+	}
+
+	log.Printf("error %+v. invalid '%s' field", http.StatusBadRequest, field)
+	return nil, fieldError(field, "must be an RFC 3339 date or an offset like +3d")
+}
+
+// parseOffset converts an offset like "3" "d" to a duration, false when it is
+// above maxOffset.
+func parseOffset(number string, unit string) (time.Duration, bool) {
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n > int64(maxOffset/units[unit]) {
+		return 0, false
+	}
+	return time.Duration(n) * units[unit], true
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2023, 4, 3, 9, 12, 30, 0, time.UTC)
+
+func TestMakeSchedule(t *testing.T) {
+	schedule, err := makeSchedule(model.MasterTask{Start: "+1d", Due: "2023-04-10T17:00:00+02:00", Reminder: "2h"}, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 4, 4, 9, 12, 0, 0, time.UTC), *schedule.Start)
+	assert.Equal(t, time.Date(2023, 4, 10, 15, 0, 0, 0, time.UTC), *schedule.Due)
+	assert.Equal(t, 2*time.Hour, schedule.Reminder)
+}
+
+func TestMakeSchedule_Empty(t *testing.T) {
+	schedule, err := makeSchedule(model.MasterTask{}, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.Schedule{}, schedule)
+}
+
+func TestMakeSchedule_Invalid(t *testing.T) {
+	for _, masterTask := range []model.MasterTask{
+		{Due: "next friday"},
+		{Due: "+3y"},
+		{Start: "2023-13-01"},
+		{Start: "+2w", Due: "+1w"},
+		{Reminder: "1h"},
+		{Due: "+3d", Reminder: "-1h"},
+		{Due: "+99999999999d"},
+		{Start: "+99999999999999999999w"},
+		{Due: "+1826d"},
+		{Due: "+3d", Reminder: "99999999999d"},
+	} {
+		_, err := makeSchedule(masterTask, now)
+		assert.ErrorIs(t, err, model.ErrInvalidRequest, "request %+v", masterTask)
+	}
+}
+
+func TestMakeSchedule_EveryField(t *testing.T) {
+	_, err := makeSchedule(model.MasterTask{Due: "tomorrow", Start: "+1y", Reminder: "+1h"}, now)
+
+	var validation *model.ValidationError
+	assert.ErrorAs(t, err, &validation)
+	assert.Equal(t, []model.FieldError{
+		{Field: "due", Reason: "must be an RFC 3339 date or an offset like +3d"},
+		{Field: "start", Reason: "must be an RFC 3339 date or an offset like +3d"},
+		{Field: "reminder", Reason: "must be an offset like 30m, 2h or 1d"},
+	}, validation.Fields)
+}
+
+func TestMakeSchedule_MaxOffset(t *testing.T) {
+	schedule, err := makeSchedule(model.MasterTask{Due: "+1825d", Reminder: "260w"}, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(1825*day).UTC().Truncate(time.Minute), *schedule.Due)
+	assert.Equal(t, 260*7*day, schedule.Reminder)
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+)
+
+const (
+	schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	schemasPath   = "/api/v1/schemas/"
+	maxFields     = 50
+)
+
+// requestField describes a field of the create request. The same rules
+// validate the requests and make the JSON Schema published per card type.
+type requestField struct {
+	name        string
+	kind        string // "string", "array" of strings or "object"
+	maxLength   int    // of the string, or of each item of an array
+	maxItems    int
+	link        bool
+	description string
+}
+
+var requestFields = []requestField{
+	{name: "type", kind: "string", maxLength: 64, description: "Card type"},
+	{name: "title", kind: "string", maxLength: 512, description: "Card title"},
+	{name: "description", kind: "string", maxLength: 16384, description: "Card description"},
+	{name: "category", kind: "string", maxLength: 64, description: "Task category, by name or alias"},
+	{name: "severity", kind: "string", maxLength: 16, description: "Bug severity"},
+	{name: "priority", kind: "string", maxLength: 16, description: "Card priority"},
+	{name: "due", kind: "string", maxLength: 64, description: "Due date, RFC 3339 date or offset like +3d"},
+	{name: "start", kind: "string", maxLength: 64, description: "Start date, RFC 3339 date or offset like +1d"},
+	{name: "reminder", kind: "string", maxLength: 16, description: "Reminder before the due date, like 30m or 1d"},
+	{name: "assignees", kind: "array", maxLength: 256, maxItems: 20, description: "Emails or usernames of the assignees"},
+	{name: "checklist", kind: "array", maxLength: 512, maxItems: maxCheckItems, description: "Checklist items"},
+	{name: "reporter", kind: "string", maxLength: 256, description: "Who reported the card"},
+	{name: "environment", kind: "string", maxLength: 2048, description: "Environment where the bug was seen"},
+	{name: "steps", kind: "array", maxLength: 1024, maxItems: 50, description: "Steps to reproduce"},
+	{name: "links", kind: "array", maxLength: 2048, maxItems: 20, link: true, description: "Related http or https URLs"},
+	{name: "fields", kind: "object", maxItems: maxFields, description: "Custom field values by field name"},
+}
+
+// Schema returns the JSON Schema of the create requests of the card type.
+func (s *TaskService) Schema(name string) (map[string]interface{}, error) {
+	cardType, ok := s.cardTypes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("card type %q: %w", name, model.ErrNotFound)
+	}
+
+	required := map[string]bool{"type": true}
+	for _, field := range cardType.Required {
+		required[field] = true
+	}
+
+	properties := map[string]interface{}{}
+	for _, field := range requestFields {
+		property := map[string]interface{}{"description": field.description}
+		switch field.kind {
+		case "string":
+			property["type"] = "string"
+			property["maxLength"] = field.maxLength
+			if required[field.name] {
+				property["minLength"] = 1
+			}
+		case "array":
+			item := map[string]interface{}{"type": "string", "minLength": 1, "maxLength": field.maxLength}
+			if field.link {
+				item["format"] = "uri"
+			}
+			property["type"] = "array"
+			property["items"] = item
+			property["maxItems"] = field.maxItems
+			if required[field.name] {
+				property["minItems"] = 1
+			}
+		case "object":
+			property["type"] = "object"
+			property["additionalProperties"] = map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+			property["maxProperties"] = field.maxItems
+			if required[field.name] {
+				property["minProperties"] = 1
+			}
+		}
+		if values := s.enum(cardType, field.name); values != nil {
+			property["pattern"] = ignoreCase(values)
+			property["examples"] = values
+		}
+		properties[field.name] = property
+	}
+
+	names := make([]string, 0, len(required))
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return map[string]interface{}{
+		"$schema":              schemaDialect,
+		"$id":                  schemasPath + cardType.Name,
+		"title":                cardType.Name + " card",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             names,
+		"additionalProperties": false,
+	}, nil
+}
+
+// enum returns the values the field accepts for the card type, or nil when
+// any value is accepted. Values are matched ignoring case, so the schema
+// publishes them as a pattern rather than a JSON Schema enum.
+func (s *TaskService) enum(cardType cardType, field string) []string {
+	switch field {
+	case "type":
+		return []string{cardType.Name}
+	case "severity":
+		return []string{model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow}
+	case "priority":
+		return []string{model.PriorityHigh, model.PriorityMedium, model.PriorityLow}
+	case "category":
+		var values []string
+		for _, c := range s.categories {
+			if len(cardType.Categories) > 0 && !contains(cardType.Categories, c.Name) {
+				continue
+			}
+			values = append(values, c.Name)
+			values = append(values, c.Aliases...)
+		}
+		sort.Strings(values)
+		return unique(values)
+	}
+	return nil
+}
+
+// validateFields checks the request against the rules of the card type and
+// reports every invalid field at once.
+func (s *TaskService) validateFields(cardType cardType, task model.MasterTask) error {
+	required := map[string]bool{}
+	for _, field := range cardType.Required {
+		required[field] = true
+	}
+
+	values := fieldValues(task)
+	var errs []model.FieldError
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, model.FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	for _, field := range requestFields {
+		switch value := values[field.name].(type) {
+		case string:
+			if strings.TrimSpace(value) == "" {
+				if required[field.name] {
+					invalid(field.name, "is required")
+				}
+				continue
+			}
+			if n := utf8.RuneCountInString(value); n > field.maxLength {
+				invalid(field.name, "must be at most %d characters, got %d", field.maxLength, n)
+			}
+			if values := s.enum(cardType, field.name); values != nil && field.name != "type" && !containsFold(values, value) {
+				invalid(field.name, "must be one of %s", strings.Join(values, ", "))
+			}
+		case []string:
+			if len(value) == 0 {
+				if required[field.name] {
+					invalid(field.name, "is required")
+				}
+				continue
+			}
+			if len(value) > field.maxItems {
+				invalid(field.name, "must have at most %d items, got %d", field.maxItems, len(value))
+			}
+			for i, item := range value {
+				name := fmt.Sprintf("%s[%d]", field.name, i)
+				switch {
+				case strings.TrimSpace(item) == "":
+					invalid(name, "must not be empty")
+				case utf8.RuneCountInString(item) > field.maxLength:
+					invalid(name, "must be at most %d characters", field.maxLength)
+				case field.link && !isLink(item):
+					invalid(name, "must be an http or https URL")
+				}
+			}
+		case map[string]interface{}:
+			if len(value) == 0 {
+				if required[field.name] {
+					invalid(field.name, "is required")
+				}
+				continue
+			}
+			if len(value) > field.maxItems {
+				invalid(field.name, "must have at most %d fields, got %d", field.maxItems, len(value))
+			}
+			for _, name := range sortedKeys(value) {
+				if strings.TrimSpace(name) == "" {
+					invalid(field.name, "must not have empty field names")
+					continue
+				}
+				switch value[name].(type) {
+				case string, float64, bool:
+				default:
+					invalid(field.name+"."+name, "must be a string, number or boolean")
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		log.Printf("error %+v. invalid fields: %+v", http.StatusBadRequest, errs)
+		return &model.ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// fieldError reports a single invalid field.
+func fieldError(field string, reason string) error {
+	return &model.ValidationError{Fields: []model.FieldError{{Field: field, Reason: reason}}}
+}
+
+// mergeFieldErrors joins the invalid fields reported by several checks into
+// one ValidationError, keeping the first reason given for each field. Other
+// errors are returned as they are.
+func mergeFieldErrors(errs ...error) error {
+	var fields []model.FieldError
+	seen := map[string]bool{}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var validation *model.ValidationError
+		if !errors.As(err, &validation) {
+			return err
+		}
+		for _, field := range validation.Fields {
+			if !seen[field.Field] {
+				seen[field.Field] = true
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &model.ValidationError{Fields: fields}
+}
+
+// fieldValues maps the request field names onto their values.
+func fieldValues(task model.MasterTask) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        task.Type,
+		"title":       task.Title,
+		"description": task.Description,
+		"category":    task.Category,
+		"severity":    task.Severity,
+		"priority":    task.Priority,
+		"due":         task.Due,
+		"start":       task.Start,
+		"reminder":    task.Reminder,
+		"assignees":   task.Assignees,
+		"checklist":   task.Checklist,
+		"reporter":    task.Reporter,
+		"environment": task.Environment,
+		"steps":       task.Steps,
+		"links":       task.Links,
+		"fields":      task.Fields,
+	}
+}
+
+func isLink(link string) bool {
+	u, err := url.Parse(strings.TrimSpace(link))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreCase is a pattern matching any of the values ignoring case. JSON
+// Schema patterns have no flags, so each letter matches both of its cases.
+func ignoreCase(values []string) string {
+	alternatives := make([]string, len(values))
+	for i, value := range values {
+		var b strings.Builder
+		for _, r := range value {
+			upper, lower := unicode.ToUpper(r), unicode.ToLower(r)
+			if upper == lower {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+				continue
+			}
+			b.WriteString("[" + string(upper) + string(lower) + "]")
+		}
+		alternatives[i] = b.String()
+	}
+	return "^(?:" + strings.Join(alternatives, "|") + ")$"
+}
+
+// unique removes the repeated values of a sorted slice.
+func unique(values []string) []string {
+	var out []string
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
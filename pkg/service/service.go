@@ -1,179 +1,662 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/bmatiasx/go-task-mgr/internal/client"
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
 	"github.com/bmatiasx/go-task-mgr/internal/model"
 )
 
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+	maxCommentSize  = 16384
+	maxCheckItems   = 200
+	checklistName   = "Checklist"
+)
+
 type Servicer interface {
 	Welcome() string
-	FilterTask(masterTask model.MasterTask) (map[string]string, error)
+	Health() model.Health
+	FilterTask(ctx context.Context, masterTask model.MasterTask) (*model.CardCreated, error)
+	Schema(cardType string) (map[string]interface{}, error)
+	GetCard(ctx context.Context, id string) (*model.Card, error)
+	UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error)
+	ArchiveCard(ctx context.Context, id string) (*model.Card, error)
+	DeleteCard(ctx context.Context, id string) error
+	TransitionCard(ctx context.Context, id string, state string) (*model.Card, error)
+	ListCards(ctx context.Context, query model.CardQuery) (*model.CardList, error)
+	AddComment(ctx context.Context, cardId string, comment model.Comment) (*model.Comment, error)
+	ListComments(ctx context.Context, cardId string) ([]model.Comment, error)
+	AddAttachment(ctx context.Context, cardId string, file model.File) (*model.Attachment, error)
+	ListChecklists(ctx context.Context, cardId string) ([]model.Checklist, error)
+	SetCheckItem(ctx context.Context, cardId string, itemId string, item model.CheckItem) (*model.CheckItem, error)
+}
+
+// Tracker is the backend where cards are stored. The Trello client is the
+// default implementation, others are selected through cfg.Config.Tracker.
+type Tracker interface {
+	CreateCard(ctx context.Context, card model.NewCard) (*model.Card, error)
+	GetCard(ctx context.Context, id string) (*model.Card, error)
+	UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error)
+	MoveCard(ctx context.Context, id string, listId string) (*model.Card, error)
+	DeleteCard(ctx context.Context, id string) error
+}
+
+// Archiver is implemented by trackers that can archive cards without deleting them.
+type Archiver interface {
+	ArchiveCard(ctx context.Context, id string) (*model.Card, error)
+}
+
+// Lister is implemented by trackers that can list and search cards.
+type Lister interface {
+	ListCards(ctx context.Context, query model.CardQuery) ([]model.Card, error)
+}
+
+// Commenter is implemented by trackers that support comments on cards.
+type Commenter interface {
+	AddComment(ctx context.Context, cardId string, text string) (*model.Comment, error)
+	ListComments(ctx context.Context, cardId string) ([]model.Comment, error)
+}
+
+// Attacher is implemented by trackers that can store files on cards.
+type Attacher interface {
+	AddAttachment(ctx context.Context, cardId string, file model.File) (*model.Attachment, error)
+}
+
+// HealthReporter is implemented by trackers with a circuit breaker.
+type HealthReporter interface {
+	Health() model.TrackerHealth
+}
+
+// Sequencer hands out the card numbers, e.g. of the bugs, which must never
+// repeat.
+type Sequencer interface {
+	Next(name string) (uint64, error)
+}
+
+// Checklister is implemented by trackers that support checklists on cards.
+type Checklister interface {
+	AddChecklist(ctx context.Context, cardId string, name string, items []string) (*model.Checklist, error)
+	ListChecklists(ctx context.Context, cardId string) ([]model.Checklist, error)
+	SetCheckItem(ctx context.Context, cardId string, itemId string, complete bool) (*model.CheckItem, error)
+}
+
+// FieldSetter is implemented by trackers with custom fields on cards. The
+// fields are checked before the card is created, so unknown names or values
+// do not leave a card behind.
+type FieldSetter interface {
+	ValidateFields(ctx context.Context, fields map[string]interface{}) error
+	SetFields(ctx context.Context, cardId string, fields map[string]interface{}) error
 }
 
 type TaskService struct {
-	client.Client
+	tracker     Tracker
+	trackerName string
+	sequence    Sequencer
+	workflow    map[string]string
+	cardTypes   map[string]cardType
+	// categories maps the lower case category names and aliases onto the
+	// categories.
+	categories map[string]cfg.Category
+	now        func() time.Time
 }
 
-func New(client client.Client) *TaskService {
-	return &TaskService{client}
+// New uses the card types and categories of the config, or the built-in ones
+// when none were loaded.
+func New(tracker Tracker, sequence Sequencer, config cfg.Config) *TaskService {
+	types := config.CardTypes
+	if len(types) == 0 {
+		types = cfg.DefaultCardTypes(config)
+	}
+	return &TaskService{
+		tracker:     tracker,
+		trackerName: config.Tracker,
+		sequence:    sequence,
+		workflow:    config.Workflow,
+		cardTypes:   newCardTypes(types),
+		categories:  newCategories(config.CategoriesFor(config.Tracker)),
+		now:         time.Now,
+	}
 }
 
 func (s *TaskService) Welcome() string {
 	return "Welcome to the Card Service!"
 }
 
-func (s *TaskService) FilterTask(masterTask model.MasterTask) (map[string]string, error) {
+// Health reports the circuit breaker state of the tracker. Trackers without
+// one are always available.
+func (s *TaskService) Health() model.Health {
+	tracker := model.TrackerHealth{Circuit: model.CircuitClosed}
+	if reporter, ok := s.tracker.(HealthReporter); ok {
+		tracker = reporter.Health()
+	}
+	tracker.Name = s.trackerName
+
+	status := model.HealthOk
+	switch tracker.Circuit {
+	case model.CircuitOpen:
+		status = model.HealthUnavailable
+	case model.CircuitHalfOpen:
+		status = model.HealthDegraded
+	}
+	return model.Health{Status: status, Tracker: tracker}
+}
+
+// FilterTask creates a card of one of the configured card types.
+func (s *TaskService) FilterTask(ctx context.Context, masterTask model.MasterTask) (*model.CardCreated, error) {
 
 	err := validateRequest(masterTask)
 	if err != nil {
 		return nil, err
 	}
 
-	switch masterTask.Type {
-	case "issue":
-		issue := model.Issue{
-			Type:        masterTask.Type,
-			Title:       masterTask.Title,
-			Description: masterTask.Description,
-		}
+	cardType, ok := s.cardTypes[strings.ToLower(masterTask.Type)]
+	if !ok {
+		log.Printf("error %+v. unknown 'type' field", http.StatusBadRequest)
+		return nil, fieldError("type", "must be one of "+strings.Join(s.typeNames(), ", "))
+	}
 
-		err := validateIssue(issue)
-		if err != nil {
-			return nil, err
-		}
+	// The dates are checked along with the other fields, so every invalid
+	// field is reported at once
+	fieldsErr := s.validateFields(cardType, masterTask)
+	schedule, scheduleErr := makeSchedule(masterTask, s.now())
+	err = mergeFieldErrors(fieldsErr, scheduleErr)
+	if err != nil {
+		return nil, err
+	}
 
-		// Call Trello API
-		res, err := s.Client.CreateIssue(issue)
-		if err != nil {
-			return nil, err
-		}
-		log.Printf("Trello card created: [id: %s, url: %s, board_id: %s, list_id: %s]",
-			res.Id, res.Url, res.BoardId, res.ListId)
-		jsonResp := map[string]string{
-			"message":     "card created",
-			"type":        issue.Type,
-			"title":       issue.Title,
-			"description": issue.Description,
-			"id":          res.Id,
-			"url":         res.Url,
-			"board_id":    res.BoardId,
-			"list_id":     res.ListId,
-		}
-		return jsonResp, nil
+	masterTask.Category, err = s.validateCategory(cardType.CardType, masterTask.Category)
+	if err != nil {
+		return nil, err
+	}
 
-	case "bug":
-		bug := model.Bug{
-			Type:        masterTask.Type,
-			Description: masterTask.Description,
-		}
+	severity, priority, err := validateSeverity(cardType.CardType, masterTask)
+	if err != nil {
+		return nil, err
+	}
+
+	checklister, err := s.checklister(masterTask.Checklist)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldSetter, err := s.fieldSetter(ctx, masterTask.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rendered once valid, so rejected cards do not take a number
+	card, err := s.makeCard(cardType, masterTask, severity, priority)
+	if err != nil {
+		return nil, err
+	}
+	card.Schedule = schedule
 
-		err := validateBug(bug)
+	// Call tracker API
+	res, err := s.tracker.CreateCard(ctx, card)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("card created: [id: %s, url: %s, board_id: %s, list_id: %s]",
+		res.Id, res.Url, res.BoardId, res.ListId)
+
+	var checklistId string
+	if checklister != nil {
+		checklist, err := checklister.AddChecklist(ctx, res.Id, checklistName, masterTask.Checklist)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("card %s created but its checklist could not be added: %w", res.Id, err)
 		}
+		checklistId = checklist.Id
+	}
 
-		// Call Trello API
-		res, err := s.Client.CreateBug(bug)
-		if err != nil {
-			return nil, err
+	if fieldSetter != nil {
+		if err := fieldSetter.SetFields(ctx, res.Id, masterTask.Fields); err != nil {
+			return nil, fmt.Errorf("card %s created but its custom fields could not be set: %w", res.Id, err)
 		}
-		log.Printf("Trello card created: [id: %s, url: %s, board_id: %s, list_id: %s]",
-			res.Id, res.Url, res.BoardId, res.ListId)
-		jsonResp := map[string]string{
-			"message":     "card created",
-			"type":        bug.Type,
-			"description": bug.Description,
-			"id":          res.Id,
-			"url":         res.Url,
-			"board_id":    res.BoardId,
-			"list_id":     res.ListId,
+	}
+
+	return &model.CardCreated{
+		Message:     "card created",
+		Type:        card.Type,
+		Title:       card.Title,
+		Description: card.Description,
+		Id:          res.Id,
+		Url:         res.Url,
+		BoardId:     res.BoardId,
+		ListId:      res.ListId,
+		Category:    card.Category,
+		Severity:    card.Severity,
+		Priority:    card.Priority,
+		ChecklistId: checklistId,
+		Due:         schedule.Due,
+		Start:       schedule.Start,
+	}, nil
+}
+
+func (s *TaskService) GetCard(ctx context.Context, id string) (*model.Card, error) {
+	if err := validateCardId(id); err != nil {
+		return nil, err
+	}
+	return s.tracker.GetCard(ctx, id)
+}
+
+func (s *TaskService) UpdateCard(ctx context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	if err := validateCardId(id); err != nil {
+		return nil, err
+	}
+	if err := validateUpdate(update); err != nil {
+		return nil, err
+	}
+
+	res, err := s.tracker.UpdateCard(ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("card updated: [id: %s, url: %s]", res.Id, res.Url)
+	return res, nil
+}
+
+func (s *TaskService) ArchiveCard(ctx context.Context, id string) (*model.Card, error) {
+	if err := validateCardId(id); err != nil {
+		return nil, err
+	}
+
+	archiver, ok := s.tracker.(Archiver)
+	if !ok {
+		return nil, fmt.Errorf("archiving cards: %w", model.ErrUnsupported)
+	}
+
+	res, err := archiver.ArchiveCard(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("card archived: [id: %s, url: %s]", res.Id, res.Url)
+	return res, nil
+}
+
+func (s *TaskService) DeleteCard(ctx context.Context, id string) error {
+	if err := validateCardId(id); err != nil {
+		return err
+	}
+
+	err := s.tracker.DeleteCard(ctx, id)
+	if err != nil {
+		return err
+	}
+	log.Printf("card deleted: [id: %s]", id)
+	return nil
+}
+
+// TransitionCard moves the card to the list mapped to the named workflow state.
+func (s *TaskService) TransitionCard(ctx context.Context, id string, state string) (*model.Card, error) {
+	if err := validateCardId(id); err != nil {
+		return nil, err
+	}
+
+	listId, ok := s.workflow[strings.ToLower(state)]
+	if !ok {
+		log.Printf("error %+v. unknown state %q", http.StatusBadRequest, state)
+		return nil, fmt.Errorf("unknown state %q, valid states are %s: %w",
+			state, strings.Join(s.states(), ", "), model.ErrInvalidRequest)
+	}
+
+	res, err := s.tracker.MoveCard(ctx, id, listId)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("card moved to %s: [id: %s, list_id: %s]", state, res.Id, res.ListId)
+	return res, nil
+}
+
+// ListCards returns a page of the cards matching the query. The cursor is the
+// opaque next_cursor of the previous page.
+func (s *TaskService) ListCards(ctx context.Context, query model.CardQuery) (*model.CardList, error) {
+	lister, ok := s.tracker.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("listing cards: %w", model.ErrUnsupported)
+	}
+
+	query, err := s.validateQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := lister.ListCards(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(cards, query.Cursor, query.Limit)
+}
+
+func (s *TaskService) validateQuery(query model.CardQuery) (model.CardQuery, error) {
+	if query.Type != "" {
+		cardType, ok := s.cardTypes[strings.ToLower(strings.TrimSpace(query.Type))]
+		if !ok {
+			log.Printf("error %+v. invalid 'type' filter", http.StatusBadRequest)
+			return query, fmt.Errorf("invalid type %q, valid types are %s: %w",
+				query.Type, strings.Join(s.typeNames(), ", "), model.ErrInvalidRequest)
 		}
-		return jsonResp, nil
+		query.Type = cardType.Name
+	}
 
-	case "task":
-		task := model.Task{
-			Type:     masterTask.Type,
-			Title:    masterTask.Title,
-			Category: masterTask.Category,
+	if query.Category != "" {
+		category, ok := s.category(query.Category)
+		if !ok {
+			log.Printf("error %+v. invalid 'category' filter", http.StatusBadRequest)
+			return query, fmt.Errorf("invalid category %q: %w", query.Category, model.ErrInvalidRequest)
 		}
+		query.Category = category.Name
+	}
 
-		err := validateTask(task)
-		if err != nil {
-			return nil, err
+	if query.ListId != "" && !cardIds.MatchString(query.ListId) {
+		log.Printf("error %+v. invalid 'list_id' filter", http.StatusBadRequest)
+		return query, fmt.Errorf("invalid list id %q: %w", query.ListId, model.ErrInvalidRequest)
+	}
+
+	if query.State != "" {
+		listId, ok := s.workflow[strings.ToLower(query.State)]
+		if !ok {
+			log.Printf("error %+v. unknown state %q", http.StatusBadRequest, query.State)
+			return query, fmt.Errorf("unknown state %q, valid states are %s: %w",
+				query.State, strings.Join(s.states(), ", "), model.ErrInvalidRequest)
 		}
+		query.ListId = listId
+	}
 
-		err = validateTaskCategory(masterTask.Category)
-		if err != nil {
-			return nil, err
+	if _, err := base64.RawURLEncoding.DecodeString(query.Cursor); err != nil {
+		log.Printf("error %+v. invalid 'cursor' parameter", http.StatusBadRequest)
+		return query, fmt.Errorf("invalid cursor: %w", model.ErrInvalidRequest)
+	}
+
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultPageSize
+	case query.Limit < 0 || query.Limit > maxPageSize:
+		log.Printf("error %+v. invalid 'limit' parameter", http.StatusBadRequest)
+		return query, fmt.Errorf("limit must be between 1 and %d: %w", maxPageSize, model.ErrInvalidRequest)
+	}
+	return query, nil
+}
+
+// paginate returns the cards following the one encoded in the cursor.
+func paginate(cards []model.Card, cursor string, limit int) (*model.CardList, error) {
+	start := 0
+	if cursor != "" {
+		id, _ := base64.RawURLEncoding.DecodeString(cursor)
+		start = -1
+		for i, card := range cards {
+			if card.Id == string(id) {
+				start = i + 1
+				break
+			}
 		}
-		// Call Trello API
-		res, err := s.Client.CreateTask(task)
-		if err != nil {
-			return nil, err
+		if start < 0 {
+			return nil, fmt.Errorf("cursor card no longer matches the query: %w", model.ErrInvalidRequest)
 		}
-		log.Printf("Trello card created: [id: %s, url: %s, board_id: %s, list_id: %s]",
-			res.Id, res.Url, res.BoardId, res.ListId)
-
-		jsonResp := map[string]string{
-			"message":  "card created",
-			"type":     task.Type,
-			"title":    task.Title,
-			"category": task.Category,
-			"id":       res.Id,
-			"url":      res.Url,
-			"board_id": res.BoardId,
-			"list_id":  res.ListId,
+	}
+
+	end := start + limit
+	if end > len(cards) {
+		end = len(cards)
+	}
+	page := model.CardList{Cards: cards[start:end]}
+	if end < len(cards) {
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(cards[end-1].Id))
+	}
+	return &page, nil
+}
+
+func (s *TaskService) AddComment(ctx context.Context, cardId string, comment model.Comment) (*model.Comment, error) {
+	commenter, err := s.commenter(cardId)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateComment(comment); err != nil {
+		return nil, err
+	}
+
+	res, err := commenter.AddComment(ctx, cardId, comment.Text)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("comment added: [card_id: %s, id: %s]", cardId, res.Id)
+	return res, nil
+}
+
+func (s *TaskService) ListComments(ctx context.Context, cardId string) ([]model.Comment, error) {
+	commenter, err := s.commenter(cardId)
+	if err != nil {
+		return nil, err
+	}
+	return commenter.ListComments(ctx, cardId)
+}
+
+func (s *TaskService) commenter(cardId string) (Commenter, error) {
+	if err := validateCardId(cardId); err != nil {
+		return nil, err
+	}
+	commenter, ok := s.tracker.(Commenter)
+	if !ok {
+		return nil, fmt.Errorf("comments: %w", model.ErrUnsupported)
+	}
+	return commenter, nil
+}
+
+func (s *TaskService) AddAttachment(ctx context.Context, cardId string, file model.File) (*model.Attachment, error) {
+	if err := validateCardId(cardId); err != nil {
+		return nil, err
+	}
+	if err := validateFile(file); err != nil {
+		return nil, err
+	}
+
+	attacher, ok := s.tracker.(Attacher)
+	if !ok {
+		return nil, fmt.Errorf("attachments: %w", model.ErrUnsupported)
+	}
+
+	res, err := attacher.AddAttachment(ctx, cardId, file)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("attachment added: [card_id: %s, id: %s, name: %s]", cardId, res.Id, res.Name)
+	return res, nil
+}
+
+func (s *TaskService) ListChecklists(ctx context.Context, cardId string) ([]model.Checklist, error) {
+	if err := validateCardId(cardId); err != nil {
+		return nil, err
+	}
+	checklister, ok := s.tracker.(Checklister)
+	if !ok {
+		return nil, fmt.Errorf("checklists: %w", model.ErrUnsupported)
+	}
+	return checklister.ListChecklists(ctx, cardId)
+}
+
+// SetCheckItem ticks the check item off, or back on, depending on its state.
+func (s *TaskService) SetCheckItem(ctx context.Context, cardId string, itemId string, item model.CheckItem) (*model.CheckItem, error) {
+	if err := validateCardId(cardId); err != nil {
+		return nil, err
+	}
+	if !cardIds.MatchString(itemId) {
+		log.Printf("error %+v. invalid check item id", http.StatusBadRequest)
+		return nil, fmt.Errorf("invalid check item id: %w", model.ErrInvalidRequest)
+	}
+	if item.State != model.CheckItemComplete && item.State != model.CheckItemIncomplete {
+		log.Printf("error %+v. invalid 'state' field", http.StatusBadRequest)
+		return nil, fmt.Errorf("state must be %q or %q: %w",
+			model.CheckItemComplete, model.CheckItemIncomplete, model.ErrInvalidRequest)
+	}
+
+	checklister, ok := s.tracker.(Checklister)
+	if !ok {
+		return nil, fmt.Errorf("checklists: %w", model.ErrUnsupported)
+	}
+
+	res, err := checklister.SetCheckItem(ctx, cardId, itemId, item.State == model.CheckItemComplete)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("check item updated: [card_id: %s, id: %s, state: %s]", cardId, res.Id, res.State)
+	return res, nil
+}
+
+// checklister returns the tracker as a Checklister when the card comes with
+// checklist items, failing before the card is created if it is not one.
+func (s *TaskService) checklister(items []string) (Checklister, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if err := validateChecklist(items); err != nil {
+		return nil, err
+	}
+	checklister, ok := s.tracker.(Checklister)
+	if !ok {
+		return nil, fmt.Errorf("checklists: %w", model.ErrUnsupported)
+	}
+	return checklister, nil
+}
+
+func (s *TaskService) fieldSetter(ctx context.Context, fields map[string]interface{}) (FieldSetter, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	for name := range fields {
+		if strings.TrimSpace(name) == "" {
+			log.Printf("error %+v. empty 'fields' name", http.StatusBadRequest)
+			return nil, fmt.Errorf("empty custom field name: %w", model.ErrInvalidRequest)
 		}
-		return jsonResp, nil
+	}
+	fieldSetter, ok := s.tracker.(FieldSetter)
+	if !ok {
+		return nil, fmt.Errorf("custom fields: %w", model.ErrUnsupported)
+	}
+	if err := fieldSetter.ValidateFields(ctx, fields); err != nil {
+		return nil, err
+	}
+	return fieldSetter, nil
+}
 
-	default:
-		return map[string]string{"message": "non recognized task type"}, nil
+func (s *TaskService) states() []string {
+	states := make([]string, 0, len(s.workflow))
+	for state := range s.workflow {
+		states = append(states, state)
 	}
+	sort.Strings(states)
+	return states
 }
 
 func validateRequest(task model.MasterTask) error {
 	if len(task.Type) == 0 {
 		log.Printf("error %+v. missing 'type' field", http.StatusBadRequest)
-		return fmt.Errorf("empty 'type' field")
+		return fieldError("type", "is required")
+	}
+	return nil
+}
+
+// validateCategory returns the name of the category matching the field, by
+// name or alias, if the card type allows it.
+func (s *TaskService) validateCategory(cardType cfg.CardType, field string) (string, error) {
+	if strings.TrimSpace(field) == "" {
+		return "", nil
+	}
+	category, ok := s.category(field)
+	if ok && len(cardType.Categories) == 0 {
+		return category.Name, nil
+	}
+	for _, v := range cardType.Categories {
+		if ok && v == category.Name {
+			return category.Name, nil
+		}
+	}
+	log.Printf("error %+v. invalid 'category' field", http.StatusBadRequest)
+	return "", fmt.Errorf("error %+v: invalid category %q: %w", http.StatusBadRequest, field, model.ErrInvalidRequest)
+}
+
+// validateSeverity returns the severity, or the default one of the card
+// type, and the priority in lower case.
+func validateSeverity(cardType cfg.CardType, task model.MasterTask) (string, string, error) {
+	severity := strings.ToLower(task.Severity)
+	if severity == "" {
+		severity = cardType.Severity
+	}
+	switch severity {
+	case "", model.SeverityCritical, model.SeverityHigh, model.SeverityMedium, model.SeverityLow:
+	default:
+		log.Printf("error %+v. invalid 'severity' field", http.StatusBadRequest)
+		return "", "", fmt.Errorf("error %+v: invalid severity %q: %w", http.StatusBadRequest, severity, model.ErrInvalidRequest)
+	}
+
+	priority := strings.ToLower(task.Priority)
+	switch priority {
+	case "", model.PriorityHigh, model.PriorityMedium, model.PriorityLow:
+	default:
+		log.Printf("error %+v. invalid 'priority' field", http.StatusBadRequest)
+		return "", "", fmt.Errorf("error %+v: invalid priority %q: %w", http.StatusBadRequest, priority, model.ErrInvalidRequest)
+	}
+	return severity, priority, nil
+}
+
+// cardIds are the card and list ids of Trello, Jira, GitHub and the local
+// store, which the clients put in request paths as they are.
+var cardIds = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func validateCardId(id string) error {
+	if !cardIds.MatchString(id) {
+		log.Printf("error %+v. invalid card id", http.StatusBadRequest)
+		return fmt.Errorf("invalid card id: %w", model.ErrInvalidRequest)
 	}
 	return nil
 }
 
-func validateIssue(issue model.Issue) error {
-	if len(issue.Title) == 0 || len(issue.Description) == 0 {
-		log.Printf("error %+v. empty 'title' or 'description' field", http.StatusBadRequest)
-		return fmt.Errorf("error %+v: empty fields", http.StatusBadRequest)
+func validateUpdate(update model.CardUpdate) error {
+	if len(update.Title) == 0 && len(update.Description) == 0 && len(update.Labels) == 0 && len(update.ListId) == 0 {
+		log.Printf("error %+v. nothing to update", http.StatusBadRequest)
+		return fmt.Errorf("nothing to update: %w", model.ErrInvalidRequest)
 	}
 	return nil
 }
 
-func validateBug(issue model.Bug) error {
-	if len(issue.Description) == 0 {
-		log.Printf("error %+v. empty 'description' field", http.StatusBadRequest)
-		return fmt.Errorf("error %+v: empty description", http.StatusBadRequest)
+func validateComment(comment model.Comment) error {
+	if len(strings.TrimSpace(comment.Text)) == 0 {
+		log.Printf("error %+v. empty 'text' field", http.StatusBadRequest)
+		return fmt.Errorf("empty comment text: %w", model.ErrInvalidRequest)
+	}
+	if len(comment.Text) > maxCommentSize {
+		log.Printf("error %+v. 'text' field too long", http.StatusBadRequest)
+		return fmt.Errorf("comment text longer than %d characters: %w", maxCommentSize, model.ErrInvalidRequest)
 	}
 	return nil
 }
 
-func validateTask(task model.Task) error {
-	if len(task.Title) == 0 || len(task.Category) == 0 {
-		log.Printf("error %+v. empty 'title' or 'category' field", http.StatusBadRequest)
-		return fmt.Errorf("error %+v: empty fields", http.StatusBadRequest)
+func validateFile(file model.File) error {
+	if len(strings.TrimSpace(file.Name)) == 0 || file.Content == nil {
+		log.Printf("error %+v. empty 'file' field", http.StatusBadRequest)
+		return fmt.Errorf("missing file: %w", model.ErrInvalidRequest)
 	}
 	return nil
 }
 
-func validateTaskCategory(category string) error {
-	c := []string{"Maintenance", "Research", "Test"}
-	for _, v := range c {
-		if v == category {
-			return nil
+func validateChecklist(items []string) error {
+	if len(items) > maxCheckItems {
+		log.Printf("error %+v. too many 'checklist' items", http.StatusBadRequest)
+		return fmt.Errorf("checklist has more than %d items: %w", maxCheckItems, model.ErrInvalidRequest)
+	}
+	for _, item := range items {
+		if len(strings.TrimSpace(item)) == 0 {
+			log.Printf("error %+v. empty 'checklist' item", http.StatusBadRequest)
+			return fmt.Errorf("empty checklist item: %w", model.ErrInvalidRequest)
 		}
 	}
-	log.Printf("error %+v. invalid 'category' field", http.StatusBadRequest)
-	return fmt.Errorf("error %+v: invalid category", http.StatusBadRequest)
+	return nil
 }
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bmatiasx/go-task-mgr/internal/cfg"
+	"github.com/bmatiasx/go-task-mgr/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTracker struct {
+	mock.Mock
+}
+
+func (m *MockTracker) CreateCard(_ context.Context, card model.NewCard) (*model.Card, error) {
+	args := m.Called(card)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTracker) GetCard(_ context.Context, id string) (*model.Card, error) {
+	args := m.Called(id)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTracker) UpdateCard(_ context.Context, id string, update model.CardUpdate) (*model.Card, error) {
+	args := m.Called(id, update)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTracker) MoveCard(_ context.Context, id string, listId string) (*model.Card, error) {
+	args := m.Called(id, listId)
+	return cardArg(args, 0), args.Error(1)
+}
+
+func (m *MockTracker) DeleteCard(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockListTracker struct {
+	MockTracker
+}
+
+func (m *MockListTracker) ListCards(_ context.Context, query model.CardQuery) ([]model.Card, error) {
+	args := m.Called(query)
+	cards, _ := args.Get(0).([]model.Card)
+	return cards, args.Error(1)
+}
+
+type MockChecklistTracker struct {
+	MockTracker
+}
+
+func (m *MockChecklistTracker) AddChecklist(_ context.Context, cardId string, name string, items []string) (*model.Checklist, error) {
+	args := m.Called(cardId, name, items)
+	c, _ := args.Get(0).(*model.Checklist)
+	return c, args.Error(1)
+}
+
+func (m *MockChecklistTracker) ListChecklists(_ context.Context, cardId string) ([]model.Checklist, error) {
+	args := m.Called(cardId)
+	c, _ := args.Get(0).([]model.Checklist)
+	return c, args.Error(1)
+}
+
+func (m *MockChecklistTracker) SetCheckItem(_ context.Context, cardId string, itemId string, complete bool) (*model.CheckItem, error) {
+	args := m.Called(cardId, itemId, complete)
+	i, _ := args.Get(0).(*model.CheckItem)
+	return i, args.Error(1)
+}
+
+type MockFieldTracker struct {
+	MockTracker
+}
+
+func (m *MockFieldTracker) ValidateFields(_ context.Context, fields map[string]interface{}) error {
+	args := m.Called(fields)
+	return args.Error(0)
+}
+
+func (m *MockFieldTracker) SetFields(_ context.Context, cardId string, fields map[string]interface{}) error {
+	args := m.Called(cardId, fields)
+	return args.Error(0)
+}
+
+type MockHealthTracker struct {
+	MockTracker
+}
+
+func (m *MockHealthTracker) Health() model.TrackerHealth {
+	args := m.Called()
+	return args.Get(0).(model.TrackerHealth)
+}
+
+// counter is an in-memory Sequencer.
+type counter struct {
+	n uint64
+}
+
+func (c *counter) Next(_ string) (uint64, error) {
+	c.n++
+	return c.n, nil
+}
+
+func cardArg(args mock.Arguments, i int) *model.Card {
+	if c, ok := args.Get(i).(*model.Card); ok {
+		return c
+	}
+	return nil
+}
+
+var testCard = &model.Card{
+	Id:      "123qwe",
+	Url:     "https://example.com/c/ueMYnIVX/69-brush-keys",
+	BoardId: "890uio",
+	ListId:  "asd456",
+}
+
+func TestTaskService_FilterTask_Issue(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"todo": "1"}})
+
+	issue := model.NewCard{Type: "issue", Title: "No pilot mode", Description: "Enable no pilot mode", ListId: "1"}
+	tracker.On("CreateCard", issue).Once().Return(testCard, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "issue", Title: "No pilot mode", Description: "Enable no pilot mode"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "card created", res.Message)
+	assert.Equal(t, testCard.Id, res.Id)
+	assert.Equal(t, testCard.ListId, res.ListId)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_Bug(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{BugLabelId: "10", Workflow: map[string]string{"todo": "1", "doing": "2"}})
+
+	tracker.On("CreateCard", model.NewCard{
+		Type:        "bug",
+		Title:       "BUG-0001: Replace old buttons",
+		Description: "Replace old buttons",
+		ListId:      "2",
+		Labels:      []string{"10"},
+		Severity:    model.SeverityCritical,
+		Priority:    model.PriorityHigh,
+	}).Once().Return(testCard, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Severity: "Critical", Priority: "high"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testCard.Url, res.Url)
+	assert.Equal(t, "critical", res.Severity)
+	assert.Equal(t, "high", res.Priority)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_BugDefaultSeverity(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"todo": "1", "doing": "2"}})
+
+	tracker.On("CreateCard", mock.MatchedBy(func(card model.NewCard) bool {
+		return card.Severity == model.SeverityMedium && card.ListId == "1"
+	})).Once().Return(testCard, nil)
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons"})
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_BugInvalidSeverity(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Severity: "blocker"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_TaskInvalidCategory(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "task", Title: "Refill oil", Category: "Cooking"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_TrackerError(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	tracker.On("CreateCard", mock.Anything).Once().Return(nil, errors.New("tracker down"))
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "task", Title: "Refill oil", Category: "Maintenance"})
+
+	assert.EqualError(t, err, "tracker down")
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_ArchiveCard_Unsupported(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.ArchiveCard(context.Background(), "123qwe")
+
+	assert.ErrorIs(t, err, model.ErrUnsupported)
+}
+
+func TestTaskService_GetCard_InvalidId(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	// The ids of GET /api/v1/cards/abc%3Ffields=all%26x= and /api/v1/cards/abc%23x
+	for _, id := range []string{"", "abc?fields=all&x=", "abc#x", "abc/actions", "abc x"} {
+		_, err := srv.GetCard(context.Background(), id)
+
+		assert.ErrorIs(t, err, model.ErrInvalidRequest, id)
+	}
+	tracker.AssertNotCalled(t, "GetCard", mock.Anything)
+}
+
+func TestTaskService_UpdateCard_Empty(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.UpdateCard(context.Background(), "123qwe", model.CardUpdate{})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNotCalled(t, "UpdateCard", mock.Anything, mock.Anything)
+}
+
+func TestTaskService_TransitionCard(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"todo": "1", "done": "3"}})
+
+	moved := &model.Card{Id: "123qwe", ListId: "3"}
+	tracker.On("MoveCard", "123qwe", "3").Once().Return(moved, nil)
+
+	res, err := srv.TransitionCard(context.Background(), "123qwe", "Done")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "3", res.ListId)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_TransitionCard_UnknownState(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"todo": "1", "done": "3"}})
+
+	_, err := srv.TransitionCard(context.Background(), "123qwe", "review")
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	assert.Contains(t, err.Error(), "done, todo")
+	tracker.AssertNotCalled(t, "MoveCard", mock.Anything, mock.Anything)
+}
+
+func TestTaskService_ListCards_Pagination(t *testing.T) {
+	tracker := new(MockListTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"doing": "2"}})
+
+	cards := []model.Card{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+	tracker.On("ListCards", mock.MatchedBy(func(q model.CardQuery) bool {
+		return q.ListId == "2" && q.Type == "bug"
+	})).Return(cards, nil)
+
+	first, err := srv.ListCards(context.Background(), model.CardQuery{Type: "bug", State: "doing", Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, cards[:2], first.Cards)
+	assert.NotEmpty(t, first.NextCursor)
+
+	second, err := srv.ListCards(context.Background(), model.CardQuery{Type: "bug", State: "doing", Limit: 2, Cursor: first.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, cards[2:], second.Cards)
+	assert.Empty(t, second.NextCursor)
+}
+
+func TestTaskService_ListCards_InvalidFilters(t *testing.T) {
+	tracker := new(MockListTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	for _, query := range []model.CardQuery{
+		{Type: "epic"},
+		{Category: "Cooking"},
+		{State: "review"},
+		{Limit: 500},
+		{Cursor: "not a cursor!"},
+		{ListId: "abc?fields=all&x="},
+		{ListId: "abc#x"},
+	} {
+		_, err := srv.ListCards(context.Background(), query)
+		assert.ErrorIs(t, err, model.ErrInvalidRequest, "query %+v", query)
+	}
+}
+
+func TestTaskService_ListCards_ConfiguredType(t *testing.T) {
+	tracker := new(MockListTracker)
+	srv := New(tracker, new(counter), cfg.Config{CardTypes: append(cfg.DefaultCardTypes(cfg.Config{}),
+		cfg.CardType{Name: "incident", Labels: []string{"10", "12"}})})
+
+	tracker.On("ListCards", mock.MatchedBy(func(query model.CardQuery) bool {
+		return query.Type == "incident"
+	})).Once().Return([]model.Card{*testCard}, nil)
+
+	res, err := srv.ListCards(context.Background(), model.CardQuery{Type: "Incident"})
+
+	assert.NoError(t, err)
+	assert.Len(t, res.Cards, 1)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_ListCards_Unsupported(t *testing.T) {
+	srv := New(new(MockTracker), new(counter), cfg.Config{})
+
+	_, err := srv.ListCards(context.Background(), model.CardQuery{})
+
+	assert.ErrorIs(t, err, model.ErrUnsupported)
+}
+
+func TestTaskService_FilterTask_TaskWithChecklist(t *testing.T) {
+	tracker := new(MockChecklistTracker)
+	srv := New(tracker, new(counter), cfg.Config{Workflow: map[string]string{"todo": "1"}})
+
+	steps := []string{"Drain old oil", "Refill oil"}
+	task := model.NewCard{
+		Type:        "task",
+		Title:       "Refill oil",
+		Description: "Belongs to category Maintenance",
+		ListId:      "1",
+		Category:    "Maintenance",
+	}
+	tracker.On("CreateCard", task).Once().Return(testCard, nil)
+	tracker.On("AddChecklist", testCard.Id, "Checklist", steps).Once().Return(&model.Checklist{Id: "cl1"}, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "task", Title: "Refill oil", Category: "Maintenance", Checklist: steps})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cl1", res.ChecklistId)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_ChecklistUnsupported(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "task", Title: "Refill oil", Category: "Maintenance", Checklist: []string{"Drain"}})
+
+	assert.ErrorIs(t, err, model.ErrUnsupported)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_BugWithFields(t *testing.T) {
+	tracker := new(MockFieldTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	fields := map[string]interface{}{"component": "dashboard", "customer": "ACME"}
+	tracker.On("ValidateFields", fields).Once().Return(nil)
+	tracker.On("CreateCard", mock.Anything).Once().Return(testCard, nil)
+	tracker.On("SetFields", testCard.Id, fields).Once().Return(nil)
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Fields: fields})
+
+	assert.NoError(t, err)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_FilterTask_UnknownField(t *testing.T) {
+	tracker := new(MockFieldTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	fields := map[string]interface{}{"owner": "dana"}
+	tracker.On("ValidateFields", fields).Once().Return(fmt.Errorf("unknown custom field: %w", model.ErrInvalidRequest))
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Fields: fields})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_FilterTask_FieldsUnsupported(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Fields: map[string]interface{}{"customer": "ACME"}})
+
+	assert.ErrorIs(t, err, model.ErrUnsupported)
+	tracker.AssertNotCalled(t, "CreateCard", mock.Anything)
+}
+
+func TestTaskService_SetCheckItem_InvalidItemId(t *testing.T) {
+	tracker := new(MockChecklistTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	for _, itemId := range []string{"", "ci1?fields=all&x=", "ci1#x", "ci1/checklist"} {
+		_, err := srv.SetCheckItem(context.Background(), "123qwe", itemId, model.CheckItem{State: model.CheckItemComplete})
+
+		assert.ErrorIs(t, err, model.ErrInvalidRequest, itemId)
+		assert.Contains(t, err.Error(), "check item id", itemId)
+	}
+	tracker.AssertNotCalled(t, "SetCheckItem", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTaskService_SetCheckItem_InvalidState(t *testing.T) {
+	tracker := new(MockChecklistTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+
+	_, err := srv.SetCheckItem(context.Background(), "123qwe", "ci1", model.CheckItem{State: "done"})
+
+	assert.ErrorIs(t, err, model.ErrInvalidRequest)
+}
+
+func TestTaskService_FilterTask_BugWithDueDate(t *testing.T) {
+	tracker := new(MockTracker)
+	srv := New(tracker, new(counter), cfg.Config{})
+	srv.now = func() time.Time { return now }
+
+	due := time.Date(2023, 4, 6, 9, 12, 0, 0, time.UTC)
+	tracker.On("CreateCard", mock.MatchedBy(func(card model.NewCard) bool {
+		return card.Due != nil && card.Due.Equal(due)
+	})).Once().Return(testCard, nil)
+
+	res, err := srv.FilterTask(context.Background(), model.MasterTask{Type: "bug", Description: "Replace old buttons", Due: "+3d"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &due, res.Due)
+	tracker.AssertExpectations(t)
+}
+
+func TestTaskService_Health(t *testing.T) {
+	for circuit, status := range map[string]string{
+		model.CircuitClosed:   model.HealthOk,
+		model.CircuitHalfOpen: model.HealthDegraded,
+		model.CircuitOpen:     model.HealthUnavailable,
+	} {
+		tracker := new(MockHealthTracker)
+		srv := New(tracker, new(counter), cfg.Config{Tracker: "trello"})
+		tracker.On("Health").Once().Return(model.TrackerHealth{Circuit: circuit, Failures: 5})
+
+		res := srv.Health()
+
+		assert.Equal(t, status, res.Status, circuit)
+		assert.Equal(t, model.TrackerHealth{Name: "trello", Circuit: circuit, Failures: 5}, res.Tracker)
+	}
+}
+
+func TestTaskService_Health_NoBreaker(t *testing.T) {
+	srv := New(new(MockTracker), new(counter), cfg.Config{Tracker: "local"})
+
+	res := srv.Health()
+
+	assert.Equal(t, model.Health{
+		Status:  model.HealthOk,
+		Tracker: model.TrackerHealth{Name: "local", Circuit: model.CircuitClosed},
+	}, res)
+}